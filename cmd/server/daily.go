@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/daily"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/filter"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+)
+
+// The daily puzzle goes by a token the server signed, handing out a new one to
+// players who don't have one yet. Lobby IDs are shown to everyone in the
+// lobby, so they can't be used for it.
+func dailyPlayerID(w http.ResponseWriter, r *http.Request) string {
+	if playerID, ok := dailyCookie(r); ok {
+		return playerID
+	}
+	playerID := session.GenerateID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     "daily",
+		Value:    daily.Token(playerID),
+		Path:     "/",
+		HttpOnly: true,
+	})
+	return playerID
+}
+
+// Who the daily cookie was issued to, if it's one the server signed
+func dailyCookie(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie("daily")
+	if err != nil {
+		return "", false
+	}
+	return daily.PlayerFor(cookie.Value)
+}
+
+// Builds the response for a play. Plays come from the daily package as copies,
+// so this can read them without its lock.
+func dailyView(play daily.Play) map[string]any {
+	view := map[string]any{
+		"date":            daily.Today(),
+		"revealed":        string(play.Game.Revealed),
+		"attempts":        play.Game.AttemptsLeft,
+		"guessed_letters": string(play.Game.GuessedLetters),
+		"status":          "playing",
+	}
+	switch play.Game.Status {
+	case game.Won:
+		view["status"] = "won"
+	case game.Lost:
+		view["status"] = "lost"
+	}
+	if play.Finished() {
+		view["word"] = play.Game.Word
+		view["attempts_used"] = play.AttemptsUsed()
+		view["seconds"] = play.Ended.Sub(play.Started).Seconds()
+	}
+	return view
+}
+
+func handleDailyState(w http.ResponseWriter, r *http.Request) {
+	playerID := dailyPlayerID(w, r)
+
+	w.Header().Set("Content-Type", "application/json")
+	play, ok := daily.Get(playerID)
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]any{"date": daily.Today(), "status": "not_started"})
+		return
+	}
+	json.NewEncoder(w).Encode(dailyView(play))
+}
+
+func handleDailyStart(w http.ResponseWriter, r *http.Request) {
+	var req DailyStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := filter.CheckPlayerName(req.PlayerName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playerID := dailyPlayerID(w, r)
+	play, err := daily.Start(playerID, req.PlayerName)
+	if errors.Is(err, daily.ErrAlreadyPlayed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Player %s started the daily puzzle", playerID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dailyView(play))
+}
+
+func handleDailyGuess(w http.ResponseWriter, r *http.Request) {
+	var req LetterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Letter) != 1 {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	playerID := dailyPlayerID(w, r)
+	play, err := daily.Guess(playerID, rune(req.Letter[0]))
+	switch {
+	case errors.Is(err, daily.ErrNotStarted):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, daily.ErrAlreadyPlayed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dailyView(play))
+}
+
+func handleDailyLeaderboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"date":    daily.Today(),
+		"results": daily.Leaderboard(),
+	})
+}
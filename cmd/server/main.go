@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Kalani-Kawaguchi/Hangman/internal/daily"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/dictionary"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/filter"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/ratelimit"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/wordbank"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/ws"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -18,6 +28,7 @@ import (
 type CreateLobbyRequest struct {
 	LobbyName string `json:"lobby_name"`
 	HostName  string `json:"host_name"`
+	session.LobbyOptions
 }
 
 type JoinLobbyRequest struct {
@@ -25,6 +36,10 @@ type JoinLobbyRequest struct {
 	PlayerName string `json:"player_name"`
 }
 
+type SpectateLobbyRequest struct {
+	SpectatorName string `json:"spectator_name"`
+}
+
 type LobbyRequest struct {
 	LobbyID string `json:"lobby_id"`
 }
@@ -33,28 +48,87 @@ type LetterRequest struct {
 	Letter string `json:"guess"`
 }
 
+type DailyStartRequest struct {
+	PlayerName string `json:"player_name"`
+}
+
 func newRest() *mux.Router {
 	r := mux.NewRouter()
+	// streams, long polls and static files aren't counted against the request
+	// limit. Websocket and SSE commands are limited per session by ws instead.
 	r.HandleFunc("/", handleRoot)
-	r.HandleFunc("/create-lobby", handleCreateLobby).Methods("POST")
-	r.HandleFunc("/join-lobby", handleJoinLobby).Methods("POST")
-	r.HandleFunc("/choose-word", handleChooseWord).Methods("POST")
-	r.HandleFunc("/guess-letter", handleGuessLetter).Methods("POST")
-	r.HandleFunc("/lobby/{id}", handleGetLobby).Methods("GET")
-	r.HandleFunc("/list-lobbies", handleListLobbies).Methods("GET")
-	r.HandleFunc("/list-games", handleListGames).Methods("POST")
-	r.HandleFunc("/leave-lobby", handleLeaveLobby).Methods("POST")
-	r.HandleFunc("/player-role", handlePlayerRole).Methods("GET")
+	r.HandleFunc("/lobby/{id}/events", ws.HandleEvents).Methods("GET")
+	r.HandleFunc("/lobby/{id}/commands", ws.HandleCommand).Methods("POST")
 	r.HandleFunc("/ws", ws.HandleWebSocket)
+	r.HandleFunc("/ws/schema", ws.HandleSchema).Methods("GET")
 	r.HandleFunc("/lobby-state", HandleLobbyState).Methods("GET")
 
+	api := r.NewRoute().Subrouter()
+	api.Use(requestLimiter.Middleware(requestKey))
+	api.HandleFunc("/create-lobby", handleCreateLobby).Methods("POST")
+	api.HandleFunc("/join-lobby", handleJoinLobby).Methods("POST")
+	api.HandleFunc("/choose-word", handleChooseWord).Methods("POST")
+	api.HandleFunc("/estimate-word", handleEstimateWord).Methods("POST")
+	api.HandleFunc("/guess-letter", handleGuessLetter).Methods("POST")
+	api.HandleFunc("/lobby/{id}", handleGetLobby).Methods("GET")
+	api.HandleFunc("/lobby/{id}/spectate", handleSpectateLobby).Methods("POST")
+	api.HandleFunc("/lobby/{id}/standings", handleStandings).Methods("GET")
+	api.HandleFunc("/lobby/{id}/words", handleUploadWords).Methods("POST")
+	api.HandleFunc("/list-lobbies", handleListLobbies).Methods("GET")
+	api.HandleFunc("/word-banks", handleListWordBanks).Methods("GET")
+	api.HandleFunc("/list-games", handleListGames).Methods("POST")
+	api.HandleFunc("/leave-lobby", handleLeaveLobby).Methods("POST")
+	api.HandleFunc("/player-role", handlePlayerRole).Methods("GET")
+	api.HandleFunc("/daily", handleDailyState).Methods("GET")
+	api.HandleFunc("/daily/start", handleDailyStart).Methods("POST")
+	api.HandleFunc("/daily/guess", handleDailyGuess).Methods("POST")
+	api.HandleFunc("/daily/leaderboard", handleDailyLeaderboard).Methods("GET")
+
 	return r
 }
 
 func main() {
+	if err := dictionary.LoadDir("./dictionaries"); err != nil {
+		log.Println("Dictionaries not loaded:", err)
+	}
+	if err := wordbank.LoadDir("./wordbanks"); err != nil {
+		log.Println("Word banks not loaded:", err)
+	}
+	// the solver and the real-word rule have to know every word the server can pick
+	words := wordbank.Words
+	if d, ok := dictionary.Get(dictionary.DefaultLanguage); ok {
+		d.Add(wordbank.AllWords()...)
+		words = d.Words()
+	}
+	// banks without difficulty tags are rated on the same scale as submitted words
+	wordbank.TagUntagged(func(word string) string {
+		return solver.EstimateWord(word, words).Band
+	})
+	configureFilter()
+	configureRateLimits()
+	configureTransport()
+	switch policy := ws.SlowConsumerPolicy(os.Getenv("HANGMAN_SLOW_CONSUMERS")); policy {
+	case ws.DropMessages, ws.Disconnect:
+		ws.SlowConsumers = policy
+	case "":
+	default:
+		log.Printf("Unknown slow consumer policy %q, disconnecting slow connections", policy)
+	}
+	daily.Configure([]byte(os.Getenv("HANGMAN_DAILY_SECRET")), wordbank.Words)
+
+	allowedOrigins := []string{"https://gohangman.vercel.app", "http://localhost:3000"}
+	if list := os.Getenv("HANGMAN_ALLOWED_ORIGINS"); list != "" {
+		allowedOrigins = strings.Split(list, ",")
+	}
+	ws.AllowedOrigins = allowedOrigins
+	if n := envInt("HANGMAN_MAX_CONNECTIONS_PER_PLAYER"); n > 0 {
+		ws.MaxConnectionsPerPlayer = n
+	}
+
 	r := newRest()
-	origins := handlers.AllowedOrigins([]string{"https://gohangman.vercel.app", "http://localhost:3000"})
-	headers := handlers.AllowedHeaders([]string{"Content-Type"})
+	origins := handlers.AllowedOrigins(allowedOrigins)
+	headers := handlers.AllowedHeaders([]string{"Content-Type", "If-None-Match", "Last-Event-ID"})
+	exposed := handlers.ExposedHeaders([]string{"ETag"})
 	methods := handlers.AllowedMethods([]string{"POST", "GET", "OPTIONS"})
 	credentials := handlers.AllowCredentials()
 
@@ -63,7 +137,108 @@ func main() {
 
 	// Start server
 	log.Println("Hangman running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(origins, methods, headers, credentials)(r)))
+	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(origins, methods, headers, exposed, credentials)(r)))
+}
+
+// Whose bucket a request comes out of. Players are told apart by their
+// session or daily token, so people behind one NAT or proxy don't use up
+// each other's requests, and anyone without either falls back to their address.
+func requestKey(r *http.Request) string {
+	lobbyID := mux.Vars(r)["id"]
+	if lobbyID == "" {
+		if cookie, err := r.Cookie("lobby"); err == nil {
+			lobbyID = cookie.Value
+		}
+	}
+	if lobbyID != "" {
+		if playerID, err := authenticateSession(r, lobbyID); err == nil {
+			return "player:" + lobbyID + ":" + playerID
+		}
+	}
+	if playerID, ok := dailyCookie(r); ok {
+		return "daily:" + playerID
+	}
+	return "ip:" + ratelimit.IP(r)
+}
+
+// Content filter settings come from the environment so operators can tighten them
+func configureFilter() {
+	if path := os.Getenv("HANGMAN_BLOCKLIST"); path != "" {
+		if err := filter.LoadBlocklist(path); err != nil {
+			log.Println("Blocklist not loaded:", err)
+		}
+	}
+	if path := os.Getenv("HANGMAN_ALLOWLIST"); path != "" {
+		if err := filter.LoadAllowlist(path); err != nil {
+			log.Println("Allowlist not loaded:", err)
+		}
+	}
+	filter.Configure(filter.Config{
+		MaxPlayerName: envInt("HANGMAN_MAX_PLAYER_NAME"),
+		MaxLobbyName:  envInt("HANGMAN_MAX_LOBBY_NAME"),
+		MaxWord:       envInt("HANGMAN_MAX_WORD"),
+	})
+}
+
+// Lobbies one address may create, refilling over a minute
+var lobbyLimiter = ratelimit.NewLimiter(ratelimit.PerMinute(5))
+
+// Requests of any kind one player, or one address if they aren't in a lobby,
+// may make, on top of the limits above
+var requestLimiter = ratelimit.NewLimiter(ratelimit.Limit{Rate: 10, Burst: 20})
+
+// Rate limits come from the environment too, in commands per second with
+// bursts of twice that
+func configureRateLimits() {
+	ratelimit.TrustProxy = os.Getenv("HANGMAN_TRUST_PROXY") != ""
+	perSecond := func(key string) ratelimit.Limit {
+		n := envInt(key)
+		return ratelimit.Limit{Rate: float64(n), Burst: 2 * n}
+	}
+	ws.ConfigureRateLimits(ws.RateLimits{
+		PerConnection: perSecond("HANGMAN_RATE_PER_CONNECTION"),
+		PerPlayer:     perSecond("HANGMAN_RATE_PER_PLAYER"),
+		PerIP:         perSecond("HANGMAN_RATE_PER_IP"),
+		MaxViolations: envInt("HANGMAN_RATE_MAX_VIOLATIONS"),
+	})
+	if n := envInt("HANGMAN_LOBBIES_PER_MINUTE"); n > 0 {
+		lobbyLimiter = ratelimit.NewLimiter(ratelimit.PerMinute(n))
+	}
+	if n := envInt("HANGMAN_REQUESTS_PER_SECOND"); n > 0 {
+		requestLimiter = ratelimit.NewLimiter(perSecond("HANGMAN_REQUESTS_PER_SECOND"))
+	}
+}
+
+// Websocket encodings and compression. JSON stays the default either way,
+// MessagePack and compression are only used by clients that ask for them.
+func configureTransport() {
+	ws.MessagePack = os.Getenv("HANGMAN_WS_MSGPACK") != "off"
+	switch level := os.Getenv("HANGMAN_WS_COMPRESSION"); level {
+	case "", "off":
+	case "on":
+		ws.EnableCompression(0)
+	default:
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 1 || n > 9 {
+			log.Printf("Unknown compression level %q, compressing at the default level", level)
+			n = 0
+		}
+		ws.EnableCompression(n)
+	}
+	if ms := os.Getenv("HANGMAN_SPECTATOR_UPDATE_MS"); ms != "" {
+		if n, err := strconv.Atoi(ms); err == nil && n >= 0 {
+			ws.SpectatorUpdateInterval = time.Duration(n) * time.Millisecond
+		}
+	}
+}
+
+// Reads a positive integer setting, 0 means unset
+func envInt(key string) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
 }
 
 func HandleLobbyState(w http.ResponseWriter, r *http.Request) {
@@ -77,15 +252,39 @@ func HandleLobbyState(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Lobby not found", http.StatusNotFound)
 		return
 	}
+
+	// A client that already has the current version, from the ETag or
+	// ?version=, is held until the lobby changes, up to ?wait= seconds
+	version := ws.LobbyVersion(lobbyID)
+	known := r.Header.Get("If-None-Match")
+	if v := r.URL.Query().Get("version"); v != "" {
+		known = lobbyETag(lobbyID, v)
+	}
+	if known == lobbyETag(lobbyID, version) {
+		if wait := longPollWait(r); wait > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			version = ws.WaitForChange(ctx, lobbyID, version)
+			cancel()
+		}
+		if known == lobbyETag(lobbyID, version) {
+			w.Header().Set("ETag", known)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("ETag", lobbyETag(lobbyID, version))
+
+	// read everything in one go so a command can't change the lobby halfway
+	lobby.StateLock.Lock()
 	lobbyState := lobby.State
 	player1Exists := lobby.Player1Exists
 	player2Exists := lobby.Player2Exists
 	player1Name := lobby.Player1
 	player2Name := lobby.Player2
-	player1Instruction := lobby.Player1Instruction
-	player2Instruction := lobby.Player2Instruction
-	player1OppInstruction := lobby.Player1OppInstruction
-	player2OppInstruction := lobby.Player2OppInstruction
+	player1Instruction := lobby.SeatInstruction(1).Prompt
+	player2Instruction := lobby.SeatInstruction(2).Prompt
+	player1OppInstruction := lobby.OpponentInstruction(1).Prompt
+	player2OppInstruction := lobby.OpponentInstruction(2).Prompt
 	player1Restarted := lobby.Player1Restarted
 	player2Restarted := lobby.Player2Restarted
 	player1RevealedWord := string(lobby.Game2.Revealed)
@@ -96,7 +295,7 @@ func HandleLobbyState(w http.ResponseWriter, r *http.Request) {
 	player2Ready := lobby.Game1Ready
 	player1Guessed := string(lobby.Game2.GuessedLetters)
 	player2Guessed := string(lobby.Game1.GuessedLetters)
-	json.NewEncoder(w).Encode(map[string]any{
+	state := map[string]any{
 		"state":                 string(lobbyState),
 		"player1Exists":         player1Exists,
 		"player2Exists":         player2Exists,
@@ -116,29 +315,73 @@ func HandleLobbyState(w http.ResponseWriter, r *http.Request) {
 		"player2Ready":          player2Ready,
 		"player1Guessed":        player1Guessed,
 		"player2Guessed":        player2Guessed,
+		"bestOf":                lobby.BestOf,
+		"round":                 lobby.Round,
+		"player1Score":          lobby.Player1Score,
+		"player2Score":          lobby.Player2Score,
+		"version":               version,
+	}
+	lobby.StateLock.Unlock()
+	json.NewEncoder(w).Encode(state)
+}
+
+// Longest a /lobby-state long poll is held open
+const maxLongPoll = 30 * time.Second
+
+func longPollWait(r *http.Request) time.Duration {
+	seconds, err := strconv.Atoi(r.URL.Query().Get("wait"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxLongPoll)
+}
+
+func lobbyETag(lobbyID string, version any) string {
+	return fmt.Sprintf(`"%s-%v"`, lobbyID, version)
+}
+
+// The session token is also returned in the response body, since the
+// websocket usually lives on another site and won't be sent this cookie
+func setSessionCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    token,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
 	})
 }
 
-func getLobbyFromCookies(r *http.Request) (*session.Lobby, string, string, error) {
-	playerCookie, err := r.Cookie("player")
+// Finds the caller's lobby from the lobby cookie and who they are from their
+// session token. The player and id cookies are only there for the frontend
+// and anyone can set them, so they aren't trusted.
+func getLobbyFromSession(r *http.Request) (*session.Lobby, string, error) {
+	lobbyCookie, err := r.Cookie("lobby")
 	if err != nil {
-		return nil, "", "", fmt.Errorf("player not identified")
+		return nil, "", fmt.Errorf("lobby not identified")
 	}
-	playerIdCookie, err := r.Cookie("id")
+	lobby, err := session.GetLobby(lobbyCookie.Value)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("player id not identified")
+		return nil, "", fmt.Errorf("lobby not found")
 	}
-	lobbyCookie, err := r.Cookie("lobby")
+	playerID, err := authenticateSession(r, lobby.ID)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("lobby not identified")
+		return nil, "", err
 	}
+	return lobby, playerID, nil
+}
 
-	lobby, err := session.GetLobby(lobbyCookie.Value)
+// Checks the session cookie was issued for the lobby to someone still in it
+func authenticateSession(r *http.Request, lobbyID string) (string, error) {
+	cookie, err := r.Cookie("session")
 	if err != nil {
-		return nil, "", "", fmt.Errorf("lobby not found")
+		return "", fmt.Errorf("no session")
 	}
-
-	return lobby, playerCookie.Value, playerIdCookie.Value, nil
+	playerID, ok := session.Authenticate(lobbyID, cookie.Value)
+	if !ok {
+		return "", fmt.Errorf("not a player in this lobby")
+	}
+	return playerID, nil
 }
 
 func handleRoot(w http.ResponseWriter, r *http.Request) {
@@ -147,44 +390,65 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 
 // Handlers
 func handleCreateLobby(w http.ResponseWriter, r *http.Request) {
+	if !lobbyLimiter.Allow(ratelimit.IP(r)) {
+		http.Error(w, "Too many lobbies created, try again later", http.StatusTooManyRequests)
+		return
+	}
+
 	var req CreateLobbyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
+	if err := req.LobbyOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// lobby is a pointer to the newly created Lobby
+	lobby, err := session.CreateLobby(req.LobbyName, req.LobbyOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Generate Unique Player ID and assign player to lobby
+	playerID := session.GenerateID()
+	_, err = session.JoinLobby(lobby.ID, req.HostName, playerID)
+	if err != nil {
+		// don't leave an empty lobby behind when the host is rejected
+		session.DeleteLobby(lobby.ID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := session.IssueToken(lobby.ID, playerID)
+	if err != nil {
+		session.DeleteLobby(lobby.ID)
+		http.Error(w, "could not start a session", http.StatusInternalServerError)
+		return
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:  "player",
 		Value: req.HostName,
 	})
-
-	// Generate Unique Player ID and save it as a cookie
-	playerID := session.GenerateID()
 	http.SetCookie(w, &http.Cookie{
 		Name:  "id",
 		Value: playerID,
 	})
-
-	// lobby is a pointer to the newly created Lobby
-	lobby := session.CreateLobby(req.LobbyName)
-
 	http.SetCookie(w, &http.Cookie{
 		Name:  "lobby",
 		Value: lobby.ID,
 	})
-
-	// assign player to lobby
-	_, err := session.JoinLobby(lobby.ID, req.HostName, playerID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	setSessionCookie(w, token)
 
 	log.Printf("Created A Lobby: %s. Host: %s", lobby.ID, playerID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"id":       lobby.ID,
 		"playerID": playerID,
+		"token":    token,
 	})
 }
 
@@ -217,12 +481,54 @@ func handleJoinLobby(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	token, err := session.IssueToken(req.LobbyID, playerID)
+	if err != nil {
+		http.Error(w, "could not start a session", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(w, token)
 	// Notify lobby that a player has joined
 	fmt.Println("Broadcasting a player join")
 	ws.BroadcastToLobby(req.LobbyID, "join")
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"playerID": playerID,
+		"token":    token,
+	})
+}
+
+func handleSpectateLobby(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	lobbyID := vars["id"]
+
+	var req SpectateLobbyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Spectators get their own ID so the websocket can tell them apart from players
+	spectatorID := session.GenerateID()
+	_, err := session.AddSpectator(lobbyID, req.SpectatorName, spectatorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	token, err := session.IssueToken(lobbyID, spectatorID)
+	if err != nil {
+		http.Error(w, "could not start a session", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(w, token)
+
+	log.Printf("Spectator %s watching lobby: %s", spectatorID, lobbyID)
+	ws.BroadcastToLobby(lobbyID, "spectate")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"lobbyID":     lobbyID,
+		"spectatorID": spectatorID,
+		"token":       token,
 	})
 }
 
@@ -235,27 +541,35 @@ func handleChooseWord(w http.ResponseWriter, r *http.Request) {
 
 	word := req.Word
 
-	if !game.ValidateWord(word) {
+	lobby_pointer, playerID, err := getLobbyFromSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	lobby_pointer, playerName, _, err := getLobbyFromCookies(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	if err := lobby_pointer.CheckWord(word); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if playerName == lobby_pointer.Player1 {
+	lobby_pointer.StateLock.Lock()
+	defer lobby_pointer.StateLock.Unlock()
+	if playerID == lobby_pointer.Player1ID {
 		lobby_pointer.Game1 = game.NewGame(word)
 		fmt.Fprintf(w, "Word: '%s' chosen for %s. \n", word, lobby_pointer.Player2)
 		lobby_pointer.Game1Ready = true
 
-	} else if playerName == lobby_pointer.Player2 {
+	} else if playerID == lobby_pointer.Player2ID {
 		lobby_pointer.Game2 = game.NewGame(word)
 		fmt.Fprintf(w, "Word: '%s' chosen for %s. \n", word, lobby_pointer.Player1)
 		lobby_pointer.Game2Ready = true
+	} else {
+		http.Error(w, "You are not seated in this lobby.", http.StatusForbidden)
+		return
 	}
 
+	estimate := lobby_pointer.EstimateWord(word)
+	fmt.Fprintf(w, "Estimated difficulty: %s (%d/100). \n", estimate.Band, estimate.Score)
 	fmt.Fprintf(w, "Game created successfully.")
 
 	if lobby_pointer.Game1Ready && lobby_pointer.Game2Ready {
@@ -263,6 +577,34 @@ func handleChooseWord(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Rates a word and checks it against the lobby's rules without setting it, so
+// a setter can see how hard it is before sending it to /choose-word
+func handleEstimateWord(w http.ResponseWriter, r *http.Request) {
+	var req session.WordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Word == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	lobby_pointer, _, err := getLobbyFromSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	reason := ""
+	if err := lobby_pointer.CheckWord(req.Word); err != nil {
+		reason = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"word":     req.Word,
+		"estimate": lobby_pointer.EstimateWord(req.Word),
+		"allowed":  reason == "",
+		"reason":   reason,
+	})
+}
+
 func handleGuessLetter(w http.ResponseWriter, r *http.Request) {
 	var req LetterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -270,12 +612,14 @@ func handleGuessLetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lobby_pointer, playerName, _, err := getLobbyFromCookies(r)
+	lobby_pointer, playerID, err := getLobbyFromSession(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	lobby_pointer.StateLock.Lock()
+	defer lobby_pointer.StateLock.Unlock()
 	if lobby_pointer.State == session.StateWaiting {
 		http.Error(w, "Lobby not ready", http.StatusUnauthorized)
 		return
@@ -283,14 +627,17 @@ func handleGuessLetter(w http.ResponseWriter, r *http.Request) {
 
 	letter := req.Letter
 
-	if playerName == lobby_pointer.Player1 {
+	if playerID == lobby_pointer.Player1ID {
 		if lobby_pointer.Game2.WinOrLost() {
 			return
 		}
-	} else if playerName == lobby_pointer.Player2 {
+	} else if playerID == lobby_pointer.Player2ID {
 		if lobby_pointer.Game1.WinOrLost() {
 			return
 		}
+	} else {
+		http.Error(w, "You are not seated in this lobby.", http.StatusForbidden)
+		return
 	}
 
 	if len(letter) != 1 {
@@ -298,9 +645,9 @@ func handleGuessLetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if playerName == lobby_pointer.Player1 {
+	if playerID == lobby_pointer.Player1ID {
 		lobby_pointer.Game2.Guess(rune(letter[0]))
-	} else if playerName == lobby_pointer.Player2 {
+	} else if playerID == lobby_pointer.Player2ID {
 		lobby_pointer.Game1.Guess(rune(letter[0]))
 	}
 }
@@ -315,7 +662,34 @@ func handleGetLobby(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(lobby)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lobby.Summary())
+}
+
+func handleStandings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	lobby, err := session.GetLobby(id)
+	if err != nil {
+		http.Error(w, "lobby not found", http.StatusNotFound)
+		return
+	}
+
+	if !lobby.IsMultiplayer() {
+		http.Error(w, "lobby has no standings", http.StatusBadRequest)
+		return
+	}
+
+	lobby.StateLock.Lock()
+	resp := map[string]any{
+		"round":     lobby.Round,
+		"remaining": len(lobby.Alive()),
+		"standings": lobby.Standings(),
+	}
+	lobby.StateLock.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func handleListLobbies(w http.ResponseWriter, r *http.Request) {
@@ -323,11 +697,64 @@ func handleListLobbies(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(lobbies)
 }
 
+// Largest custom word list body the server will read
+const maxWordListBytes = 256 << 10
+
+func handleUploadWords(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	lobby, err := session.GetLobby(id)
+	if err != nil {
+		http.Error(w, "lobby not found", http.StatusNotFound)
+		return
+	}
+	playerID, err := authenticateSession(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if playerID != lobby.Player1ID {
+		http.Error(w, "Only the lobby host can upload words.", http.StatusForbidden)
+		return
+	}
+
+	entries, rejected, err := wordbank.ParseCustom(http.MaxBytesReader(w, r.Body, maxWordListBytes), "custom")
+	if err != nil {
+		http.Error(w, "invalid word list: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	accepted, filtered := lobby.SetCustomWords(entries)
+	rejected = append(rejected, filtered...)
+	if accepted == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"accepted": 0,
+			"rejected": rejected,
+		})
+		return
+	}
+
+	log.Printf("Lobby %s loaded %d custom words", lobby.ID, accepted)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"accepted": accepted,
+		"rejected": rejected,
+	})
+}
+
+func handleListWordBanks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wordbank.List())
+}
+
 func handleLeaveLobby(w http.ResponseWriter, r *http.Request) {
-	// Get player name and lobby id from cookies
-	lobby, _, playerID, err := getLobbyFromCookies(r)
+	lobby, playerID, err := getLobbyFromSession(r)
 	if err != nil {
-		log.Println("Error getting lobby from cookies")
+		log.Println("Error getting lobby from session")
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
@@ -336,14 +763,20 @@ func handleLeaveLobby(w http.ResponseWriter, r *http.Request) {
 
 	// Check which player is trying to leave
 	if playerID == lobby.Player1ID {
+		lobby.StateLock.Lock()
 		lobby.PlayerCount = "0"
+		lobby.StateLock.Unlock()
 		ws.BroadcastToLobby(lobby_id, "closeAll")
 		return
 
 	} else if playerID == lobby.Player2ID {
-		// just have player2 leave the lobby
+		// just have player2 leave the lobby, taking their ID and session with them
+		lobby.StateLock.Lock()
 		lobby.Player2 = ""
+		lobby.Player2ID = ""
 		lobby.PlayerCount = "1"
+		lobby.StateLock.Unlock()
+		session.RevokeTokens(lobby_id, playerID)
 
 		// update player2 lobby cookie and lobby player2 info
 		http.SetCookie(w, &http.Cookie{
@@ -356,6 +789,17 @@ func handleLeaveLobby(w http.ResponseWriter, r *http.Request) {
 		ws.BroadcastToLobby(lobby_id, "closeOne")
 		return
 
+	} else if lobby.IsMultiplayer() && ws.MemberLeft(lobby_id, playerID) {
+		log.Printf("Player %s left, %s players remaining", playerID, lobby.PlayerCount)
+
+		http.SetCookie(w, &http.Cookie{
+			Name:   "lobby",
+			Value:  "",
+			Path:   "/",
+			MaxAge: -1,
+		})
+		return
+
 	} else {
 		// return error, since this player shouldn't be in this lobby
 		http.Error(w, "You are not part of this lobby.", http.StatusUnauthorized)
@@ -376,16 +820,36 @@ func handleListGames(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lobby.StateLock.Lock()
 	resp := map[string]interface{}{
-		"game1":      lobby.Game1,
-		"game2":      lobby.Game2,
+		"game1":      maskedGame(lobby.Game1),
+		"game2":      maskedGame(lobby.Game2),
 		"game1Ready": strconv.FormatBool(lobby.Game1Ready),
 		"game2Ready": strconv.FormatBool(lobby.Game2Ready),
 	}
+	lobby.StateLock.Unlock()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// What anyone may see of a board. The word itself is never included.
+func maskedGame(g game.Game) map[string]any {
+	status := "playing"
+	switch g.Status {
+	case game.Won:
+		status = "won"
+	case game.Lost:
+		status = "lost"
+	}
+	return map[string]any{
+		"revealed":        string(g.Revealed),
+		"attempts":        g.AttemptsLeft,
+		"guessed_letters": string(g.GuessedLetters),
+		"status":          status,
+		"category":        g.Category,
+	}
+}
+
 func handlePlayerRole(w http.ResponseWriter, r *http.Request) {
 	lobbyID := r.URL.Query().Get("lobby")
 	playerID := r.URL.Query().Get("id")
@@ -396,6 +860,7 @@ func handlePlayerRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lobby.StateLock.Lock()
 	role := "guest"
 	name := lobby.Player2
 	opponent := lobby.Player1
@@ -405,9 +870,16 @@ func handlePlayerRole(w http.ResponseWriter, r *http.Request) {
 		opponent = lobby.Player2
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	resp := map[string]string{
 		"role":     role,
 		"name":     name,
 		"opponent": opponent,
-	})
+	}
+	if member := lobby.Member(playerID); member != nil && member.Team != 0 {
+		resp["name"] = member.Name
+		resp["team"] = strconv.Itoa(member.Team)
+	}
+	lobby.StateLock.Unlock()
+
+	json.NewEncoder(w).Encode(resp)
 }
@@ -18,6 +18,7 @@ export default function CreateLobby() {
     interface CreateLobbyResponse {
         id: string;
         playerID: string;
+        token: string;
     }
 
     const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
@@ -36,6 +37,8 @@ export default function CreateLobby() {
         });
         if (res.ok) {
             const lobby: CreateLobbyResponse = await res.json();
+            // the token stays out of the URL so it doesn't end up in history or logs
+            sessionStorage.setItem(`token:${lobby.id}`, lobby.token);
             router.push(`/lobby?lobby=${lobby.id}&playerID=${lobby.playerID}`);
         } else {
             setLoading(false);
@@ -36,6 +36,7 @@ export default function JoinLobby() {
 
     interface CreateResponse {
         playerID: string;
+        token: string;
     }
 
     const joinLobby = async (lobbyId: string): Promise<void> => {
@@ -52,6 +53,8 @@ export default function JoinLobby() {
         });
         if (res.ok) {
             const resp: CreateResponse = await res.json();
+            // the token stays out of the URL so it doesn't end up in history or logs
+            sessionStorage.setItem(`token:${lobbyId}`, resp.token);
             router.push(`/lobby?lobby=${lobbyId}&playerID=${resp.playerID}`);
         } else {
             alert('Failed to join lobby');
@@ -64,8 +64,9 @@ export default function Lobby() {
         // Only create the websocket if it doesn't already exist
         if (ws.current) return;
 
-        const socket = new WebSocket(`wss://hangman-qrdh.onrender.com/ws?lobby=${lobbyId}&id=${playerId}`);
-        // const socket = new WebSocket(`ws://localhost:8080/ws?lobby=${lobbyId}&id=${playerId}`);
+        const token = sessionStorage.getItem(`token:${lobbyId}`) ?? '';
+        const socket = new WebSocket(`wss://hangman-qrdh.onrender.com/ws?lobby=${lobbyId}&id=${playerId}&token=${token}`);
+        // const socket = new WebSocket(`ws://localhost:8080/ws?lobby=${lobbyId}&id=${playerId}&token=${token}`);
         ws.current = socket;
 
         socket.onopen = () => {
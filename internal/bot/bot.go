@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"math/rand"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+)
+
+// Difficulty is how well the bot plays, on the same easy to hard scale as words
+type Difficulty string
+
+const (
+	Easy   Difficulty = solver.Easy   // guesses random letters
+	Medium Difficulty = solver.Medium // guesses by english letter frequency
+	Hard   Difficulty = solver.Hard   // narrows a dictionary down to the words that still fit
+)
+
+// English letters from most to least common
+const letterFrequency = "etaoinshrdlcumwfgypbvkjxqz"
+
+// ValidDifficulty reports whether d is a known bot difficulty
+func ValidDifficulty(d Difficulty) bool {
+	return solver.BandIndex(string(d)) >= 0
+}
+
+// NextGuess picks the bot's next letter for g. words is the dictionary used by
+// the hard bot to filter candidates.
+func NextGuess(d Difficulty, g game.Game, words []string) rune {
+	switch d {
+	case Easy:
+		return randomGuess(g)
+	case Hard:
+		if letter, ok := dictionaryGuess(g, words); ok {
+			return letter
+		}
+	}
+	return frequencyGuess(g)
+}
+
+func randomGuess(g game.Game) rune {
+	var open []rune
+	for _, letter := range letterFrequency {
+		if !g.Letters[letter] {
+			open = append(open, letter)
+		}
+	}
+	return open[rand.Intn(len(open))]
+}
+
+func frequencyGuess(g game.Game) rune {
+	for _, letter := range letterFrequency {
+		if !g.Letters[letter] {
+			return letter
+		}
+	}
+	return 'e'
+}
+
+// Lets the solver filter the dictionary down to the words that still fit and
+// pick the letter that narrows them down the most
+func dictionaryGuess(g game.Game, words []string) (rune, bool) {
+	return solver.Best(string(g.Revealed), g.GuessedLetters, words)
+}
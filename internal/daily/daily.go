@@ -0,0 +1,246 @@
+package daily
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/wordbank"
+)
+
+// Dates are keyed in UTC so everyone gets the same puzzle at the same moment
+const dateFormat = "2006-01-02"
+
+var (
+	ErrAlreadyPlayed = errors.New("you have already played today's puzzle")
+	ErrNotStarted    = errors.New("start today's puzzle first")
+	ErrNoWords       = errors.New("no words to pick the daily puzzle from")
+)
+
+// Play is one player's attempt at a day's puzzle
+type Play struct {
+	PlayerID string
+	Name     string
+	Game     game.Game
+	Started  time.Time
+	Ended    time.Time
+}
+
+// Result is a finished play as shown on the leaderboard
+type Result struct {
+	Name         string  `json:"name"`
+	Won          bool    `json:"won"`
+	AttemptsUsed int     `json:"attemptsUsed"`
+	Seconds      float64 `json:"seconds"`
+}
+
+var (
+	secret []byte
+	words  []string
+	plays  = make(map[string]map[string]*Play) // date -> player ID -> play
+	mu     sync.Mutex
+)
+
+// Configure sets the server secret that keeps the word of the day unguessable
+// and the list it's drawn from. A random secret is made when none is given,
+// which changes the puzzle whenever the server restarts.
+func Configure(key []byte, list []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	secret = key
+	words = list
+}
+
+// Today is the current puzzle date
+func Today() string {
+	return time.Now().UTC().Format(dateFormat)
+}
+
+// WordFor picks the word for a date, the same for every caller with the same secret
+func WordFor(date string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return wordFor(date)
+}
+
+func wordFor(date string) (string, error) {
+	list := words
+	if len(list) == 0 {
+		list = wordbank.Words
+	}
+	if len(list) == 0 {
+		return "", ErrNoWords
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(date))
+	sum := mac.Sum(nil)
+	return list[binary.BigEndian.Uint64(sum[:8])%uint64(len(list))], nil
+}
+
+// Token signs a player ID with the server secret. Plays are keyed on the ID
+// inside a token the server handed out, so nobody can take over someone
+// else's play by claiming their ID.
+func Token(playerID string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	return playerID + "." + sign(playerID)
+}
+
+// PlayerFor returns the player ID a token was issued for, if the server signed it
+func PlayerFor(token string) (string, bool) {
+	playerID, signature, ok := strings.Cut(token, ".")
+	if !ok || playerID == "" {
+		return "", false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !hmac.Equal([]byte(signature), []byte(sign(playerID))) {
+		return "", false
+	}
+	return playerID, true
+}
+
+func sign(playerID string) string {
+	// prefixed so a signature can never double as a date's word pick
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("player:" + playerID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start begins today's puzzle for a player, or hands back the play they already
+// have going. Each player ID gets one go per day.
+func Start(playerID, name string) (Play, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	date := Today()
+	prune(date)
+	if play, ok := plays[date][playerID]; ok {
+		if play.Finished() {
+			return play.snapshot(), ErrAlreadyPlayed
+		}
+		return play.snapshot(), nil
+	}
+
+	word, err := wordFor(date)
+	if err != nil {
+		return Play{}, err
+	}
+	if plays[date] == nil {
+		plays[date] = make(map[string]*Play)
+	}
+	play := &Play{PlayerID: playerID, Name: name, Game: game.NewGame(word), Started: time.Now()}
+	plays[date][playerID] = play
+	return play.snapshot(), nil
+}
+
+// Guess plays a letter in the player's puzzle for today
+func Guess(playerID string, letter rune) (Play, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	play, ok := plays[Today()][playerID]
+	if !ok {
+		return Play{}, ErrNotStarted
+	}
+	if play.Finished() {
+		return play.snapshot(), ErrAlreadyPlayed
+	}
+
+	play.Game.Guess(letter)
+	if play.Finished() {
+		play.Ended = time.Now()
+	}
+	return play.snapshot(), nil
+}
+
+// Get returns the player's play for today, if they've started one
+func Get(playerID string) (Play, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	play, ok := plays[Today()][playerID]
+	if !ok {
+		return Play{}, false
+	}
+	return play.snapshot(), true
+}
+
+// Leaderboard ranks today's finished plays: solvers first, then by fewest
+// wrong guesses, then by fastest time
+func Leaderboard() []Result {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var results []Result
+	for _, play := range plays[Today()] {
+		if !play.Finished() {
+			continue
+		}
+		results = append(results, Result{
+			Name:         play.Name,
+			Won:          play.Game.Status == game.Won,
+			AttemptsUsed: play.AttemptsUsed(),
+			Seconds:      play.Ended.Sub(play.Started).Seconds(),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Won != b.Won {
+			return a.Won
+		}
+		if a.AttemptsUsed != b.AttemptsUsed {
+			return a.AttemptsUsed < b.AttemptsUsed
+		}
+		return a.Seconds < b.Seconds
+	})
+	return results
+}
+
+// Finished reports whether the puzzle has been solved or lost
+func (p *Play) Finished() bool {
+	return p.Game.Status != game.InProgress
+}
+
+// AttemptsUsed is how many wrong guesses the player has made
+func (p *Play) AttemptsUsed() int {
+	return game.MaxAttempts - p.Game.AttemptsLeft
+}
+
+// Copies a play for a caller to read once mu is released, another request for
+// the same player can be guessing into the original
+func (p *Play) snapshot() Play {
+	play := *p
+	play.Game.Revealed = append([]rune(nil), p.Game.Revealed...)
+	play.Game.GuessedLetters = append([]rune(nil), p.Game.GuessedLetters...)
+	play.Game.History = append([]rune(nil), p.Game.History...)
+	play.Game.Letters = make(map[rune]bool, len(p.Game.Letters))
+	for letter, seen := range p.Game.Letters {
+		play.Game.Letters[letter] = seen
+	}
+	return play
+}
+
+// Drops plays from earlier days, nobody can still be playing them
+func prune(today string) {
+	for date := range plays {
+		if date != today {
+			delete(plays, date)
+		}
+	}
+}
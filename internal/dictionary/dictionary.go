@@ -0,0 +1,162 @@
+package dictionary
+
+import (
+	"bufio"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+)
+
+// Language used when a lobby doesn't pick one
+const DefaultLanguage = "en"
+
+// Dictionary is a word list for one language. Files list one word per line,
+// most common first, so a word's line number doubles as its frequency rank.
+type Dictionary struct {
+	Language string
+	words    []string
+	rank     map[string]int
+}
+
+// Loaded dictionaries by language
+var (
+	dictionaries   = make(map[string]*Dictionary)
+	dictionariesMu sync.RWMutex
+)
+
+// Load reads a word list file. Blank lines, comments starting with # and words
+// with anything other than english letters are skipped.
+func Load(language string, path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := &Dictionary{Language: language, rank: make(map[string]int)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if _, dup := d.rank[word]; dup || !game.ValidateWord(word) {
+			continue
+		}
+		d.rank[word] = len(d.words)
+		d.words = append(d.words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(d.words) == 0 {
+		return nil, errors.New("dictionary " + path + " has no words")
+	}
+	return d, nil
+}
+
+// LoadDir loads every <language>.txt file in dir and registers it
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no dictionaries found in " + dir)
+	}
+
+	for _, path := range paths {
+		language := strings.TrimSuffix(filepath.Base(path), ".txt")
+		d, err := Load(language, path)
+		if err != nil {
+			return err
+		}
+		Register(d)
+	}
+	return nil
+}
+
+// Register makes a dictionary available by its language
+func Register(d *Dictionary) {
+	dictionariesMu.Lock()
+	defer dictionariesMu.Unlock()
+	dictionaries[d.Language] = d
+}
+
+// Get returns the dictionary for a language, if one was loaded
+func Get(language string) (*Dictionary, bool) {
+	dictionariesMu.RLock()
+	defer dictionariesMu.RUnlock()
+	d, ok := dictionaries[language]
+	return d, ok
+}
+
+// Contains reports whether word is in the dictionary, ignoring case
+func (d *Dictionary) Contains(word string) bool {
+	_, ok := d.rank[strings.ToLower(word)]
+	return ok
+}
+
+// Rank returns the word's position in the frequency ordered list, 0 being the most common
+func (d *Dictionary) Rank(word string) (int, bool) {
+	rank, ok := d.rank[strings.ToLower(word)]
+	return rank, ok
+}
+
+// Len returns the number of words in the dictionary
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Words returns every word in frequency order. The slice must not be modified.
+func (d *Dictionary) Words() []string {
+	return d.words
+}
+
+// Random returns any word from the dictionary
+func (d *Dictionary) Random() string {
+	return d.words[rand.Intn(len(d.words))]
+}
+
+// RandomOfLength returns a random word with exactly n letters
+func (d *Dictionary) RandomOfLength(n int) (string, bool) {
+	return d.randomWhere(func(word string) bool { return len(word) == n })
+}
+
+// RandomByDifficulty returns a random word the solver rates in band, one of
+// solver.Bands. Words are rated as they're tried, so this stops at the first fit.
+func (d *Dictionary) RandomByDifficulty(band string) (string, bool) {
+	if solver.BandIndex(band) < 0 {
+		return "", false
+	}
+	return d.randomWhere(func(word string) bool { return solver.EstimateWord(word, d.words).Band == band })
+}
+
+// Tries the words in a random order, returning the first one keep accepts
+func (d *Dictionary) randomWhere(keep func(string) bool) (string, bool) {
+	for _, i := range rand.Perm(len(d.words)) {
+		if keep(d.words[i]) {
+			return d.words[i], true
+		}
+	}
+	return "", false
+}
+
+// Add appends the words the dictionary doesn't have yet as its rarest entries.
+// Dictionaries aren't locked, so this is only for setting up before serving.
+func (d *Dictionary) Add(words ...string) {
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if _, dup := d.rank[word]; dup || !game.ValidateWord(word) {
+			continue
+		}
+		d.rank[word] = len(d.words)
+		d.words = append(d.words, word)
+	}
+}
@@ -0,0 +1,93 @@
+package dictionary
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+)
+
+func testDictionary(t *testing.T) *Dictionary {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "en.txt")
+	list := "the\nand\nhouse\nwater\npeople\n# comment\nbanana\njazz\nquiz\nrhythm\nsyzygy\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Load("en", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestRandom(t *testing.T) {
+	d := testDictionary(t)
+	for i := 0; i < 20; i++ {
+		if word := d.Random(); !d.Contains(word) {
+			t.Errorf("Random() = %q, not in the dictionary", word)
+		}
+	}
+}
+
+func TestRandomOfLength(t *testing.T) {
+	d := testDictionary(t)
+	tests := []struct {
+		n  int
+		ok bool
+	}{
+		{3, true},
+		{4, true},
+		{5, true},
+		{6, true},
+		{2, false},
+		{12, false},
+	}
+	for _, tt := range tests {
+		word, ok := d.RandomOfLength(tt.n)
+		if ok != tt.ok {
+			t.Errorf("RandomOfLength(%d) ok = %v, want %v", tt.n, ok, tt.ok)
+			continue
+		}
+		if ok && len(word) != tt.n {
+			t.Errorf("RandomOfLength(%d) = %q", tt.n, word)
+		}
+	}
+}
+
+func TestRandomByDifficulty(t *testing.T) {
+	d := testDictionary(t)
+	found := 0
+	for _, band := range solver.Bands {
+		word, ok := d.RandomByDifficulty(band)
+		if !ok {
+			continue
+		}
+		found++
+		if got := solver.EstimateWord(word, d.Words()).Band; got != band {
+			t.Errorf("RandomByDifficulty(%q) = %q, which is %q", band, word, got)
+		}
+	}
+	if found == 0 {
+		t.Error("RandomByDifficulty found no word in any band")
+	}
+	if word, ok := d.RandomByDifficulty("impossible"); ok {
+		t.Errorf("RandomByDifficulty(\"impossible\") = %q, want none", word)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	d := testDictionary(t)
+	n := d.Len()
+	d.Add("House", "zebra", "not a word")
+	if d.Len() != n+1 || !d.Contains("zebra") {
+		t.Errorf("Add kept %d new words, want just zebra", d.Len()-n)
+	}
+	if rank, _ := d.Rank("zebra"); rank != n {
+		t.Errorf("zebra ranked %d, want %d as the rarest", rank, n)
+	}
+	if d.Words()[0] != "the" {
+		t.Errorf("Words()[0] = %q, want the most common first", d.Words()[0])
+	}
+}
@@ -0,0 +1,317 @@
+package filter
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed blocklist.txt
+var defaultBlocklist string
+
+//go:embed allowlist.txt
+var defaultAllowlist string
+
+// Config holds the length limits and blocked terms applied to user supplied text
+type Config struct {
+	MaxPlayerName int
+	MaxLobbyName  int
+	MaxWord       int
+	Blocklist     []string
+	Allowlist     []string // words containing a blocked term that are let through
+}
+
+var (
+	config = Config{
+		MaxPlayerName: 20,
+		MaxLobbyName:  30,
+		MaxWord:       30,
+		Blocklist:     parseList(defaultBlocklist),
+		Allowlist:     parseList(defaultAllowlist),
+	}
+	configMu sync.RWMutex
+)
+
+// Configure replaces the active filter settings. Zero limits keep the current values.
+func Configure(c Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if c.MaxPlayerName > 0 {
+		config.MaxPlayerName = c.MaxPlayerName
+	}
+	if c.MaxLobbyName > 0 {
+		config.MaxLobbyName = c.MaxLobbyName
+	}
+	if c.MaxWord > 0 {
+		config.MaxWord = c.MaxWord
+	}
+	if c.Blocklist != nil {
+		config.Blocklist = normalizeAll(c.Blocklist)
+	}
+	if c.Allowlist != nil {
+		config.Allowlist = normalizeAll(c.Allowlist)
+	}
+}
+
+func normalizeAll(terms []string) []string {
+	normalized := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = Normalize(term); term != "" {
+			normalized = append(normalized, term)
+		}
+	}
+	return normalized
+}
+
+// LoadBlocklist reads one blocked term per line from a file, replacing the built in list
+func LoadBlocklist(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	Configure(Config{Blocklist: parseList(string(data))})
+	return nil
+}
+
+// LoadAllowlist reads one allowed word per line from a file, replacing the built in list
+func LoadAllowlist(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	Configure(Config{Allowlist: parseList(string(data))})
+	return nil
+}
+
+func parseList(raw string) []string {
+	var terms []string
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if term := Normalize(line); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Digits and symbols commonly swapped in for letters
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '2': 'z', '3': 'e', '4': 'a', '5': 's', '6': 'g', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't', '(': 'c', '€': 'e', '£': 'l',
+}
+
+// Non latin characters that look like latin letters
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	'ԛ': 'q', 'ԝ': 'w', 'һ': 'h',
+	// Greek
+	'α': 'a', 'β': 'b', 'γ': 'y', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Latin look-alikes
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'ß': 's', 'æ': 'a', 'đ': 'd', 'ħ': 'h',
+}
+
+// Normalize folds text down to plain lowercase ascii letters so blocked terms
+// can't be dodged with case, leetspeak, accents, full width or look-alike
+// characters, or by putting spaces and punctuation between letters
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			// full width forms map straight onto ascii
+			r = unicode.ToLower(r - 0xFF01 + '!')
+		}
+		if mapped, ok := leetspeak[r]; ok {
+			r = mapped
+		} else if mapped, ok := confusables[r]; ok {
+			r = mapped
+		} else if r > unicode.MaxASCII {
+			r = stripAccent(r)
+		}
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Latin letters with diacritics, grouped by their base letter
+var accented = map[rune]string{
+	'a': "àáâãäåāăą", 'c': "çćĉċč", 'd': "ď", 'e': "èéêëēĕėęě", 'g': "ĝğġģ", 'h': "ĥ",
+	'i': "ìíîïĩīĭįİ", 'j': "ĵ", 'k': "ķ", 'l': "ĺļľŀ", 'n': "ñńņňŉ", 'o': "òóôõöōŏő",
+	'r': "ŕŗř", 's': "śŝşš", 't': "ţťŧ", 'u': "ùúûüũūŭůűų", 'w': "ŵ", 'y': "ýÿŷ", 'z': "źżž",
+}
+
+func stripAccent(r rune) rune {
+	for base, variants := range accented {
+		if strings.ContainsRune(variants, r) {
+			return base
+		}
+	}
+	return r
+}
+
+// collapse squeezes runs of the same letter, so "fuuuck" matches "fuck"
+func collapse(text string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range text {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// words splits text into normalized words at spaces and punctuation, keeping
+// the digits and symbols that stand in for letters. Runs of single letters,
+// as in "s h i t" or "f.u.c.k", are joined back into one word.
+func words(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		if _, ok := leetspeak[r]; ok {
+			return false
+		}
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+
+	var found []string
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 1 {
+			found = append(found, run.String())
+		}
+		run.Reset()
+	}
+	for _, field := range fields {
+		word := Normalize(field)
+		// a symbol at the end is more likely punctuation than leetspeak, as in "shit!"
+		trimmed := Normalize(strings.TrimFunc(field, func(r rune) bool { return !unicode.IsLetter(r) }))
+		letter := trimmed
+		if letter == "" {
+			letter = word
+		}
+		if len(letter) == 1 {
+			run.WriteString(letter)
+			continue
+		}
+		flush()
+		if word != "" {
+			found = append(found, word)
+		}
+		if trimmed != "" && trimmed != word {
+			found = append(found, trimmed)
+		}
+	}
+	flush()
+	return found
+}
+
+// Takes allowed words out of word, leaving a gap so the pieces either side
+// can't join up into a blocked term. The caller holds configMu.
+func withoutAllowed(word string) string {
+	for _, allowed := range config.Allowlist {
+		word = strings.ReplaceAll(word, allowed, " ")
+	}
+	return word
+}
+
+// matches reports whether term appears anywhere in word, possibly stretched
+// like "fuuuck". The caller holds configMu.
+func matches(word string, term string) bool {
+	if strings.Contains(word, term) {
+		return true
+	}
+	// only stretched words are compared collapsed, so "niger" isn't "nigger"
+	if collapsed := collapse(word); collapsed != word {
+		return strings.Contains(withoutAllowed(collapsed), collapse(term))
+	}
+	return false
+}
+
+// Blocked reports whether any word of text contains a blocked term once
+// normalized. Terms are matched inside words so compounds like "bullshit"
+// are caught, words only split at spaces and punctuation so "push it" isn't,
+// and allowlisted words like "Scunthorpe" are skipped.
+func Blocked(text string) bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	for _, word := range words(text) {
+		word = withoutAllowed(word)
+		for _, term := range config.Blocklist {
+			if matches(word, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func check(kind string, text string, max int) error {
+	text = strings.TrimSpace(text)
+	length := utf8.RuneCountInString(text)
+	if length == 0 {
+		return fmt.Errorf("%s can't be empty", kind)
+	}
+	if length > max {
+		return fmt.Errorf("%s must be at most %d characters", kind, max)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s contains invalid characters", kind)
+		}
+	}
+	if Blocked(text) {
+		return fmt.Errorf("%s contains blocked language", kind)
+	}
+	return nil
+}
+
+// CheckPlayerName validates a player or spectator name
+func CheckPlayerName(name string) error {
+	configMu.RLock()
+	max := config.MaxPlayerName
+	configMu.RUnlock()
+	return check("player name", name, max)
+}
+
+// CheckLobbyName validates the name a host gives their lobby
+func CheckLobbyName(name string) error {
+	configMu.RLock()
+	max := config.MaxLobbyName
+	configMu.RUnlock()
+	return check("lobby name", name, max)
+}
+
+// CheckWord validates a word submitted for someone to guess
+func CheckWord(word string) error {
+	configMu.RLock()
+	max := config.MaxWord
+	configMu.RUnlock()
+	return check("word", word, max)
+}
+
+// ErrBlocked is returned for free text, like chat, that contains blocked language
+var ErrBlocked = errors.New("message contains blocked language")
+
+// CheckMessage rejects free text containing blocked language
+func CheckMessage(text string) error {
+	if Blocked(text) {
+		return ErrBlocked
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package filter
+
+import "testing"
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		text    string
+		blocked bool
+	}{
+		{"shit", true},
+		{"SHIT", true},
+		{"sh1t", true},
+		{"$hit", true},
+		{"ѕhit", true},
+		{"what the shit!", true},
+		{"s h i t", true},
+		{"f.u.c.k", true},
+		{"fuuuuck", true},
+		{"fucking", true},
+		{"shitty", true},
+		{"bitches", true},
+		{"you retard", true},
+		{"motherfucker", true},
+		{"bullshit", true},
+		{"fuckyou", true},
+		{"fuckface", true},
+		{"shithead", true},
+		{"dumbass", true},
+		{"bitchass", true},
+		{"shitshow", true},
+		{"scunthorpeshit", true},
+		{"push it", false},
+		{"finish it", false},
+		{"Scunthorpe", false},
+		{"retardant", false},
+		{"shiitake", false},
+		{"Niger", false},
+		{"sniggering", false},
+		{"niggardly", false},
+		{"retardation", false},
+		{"class", false},
+		{"assassin", false},
+		{"cocktail", false},
+		{"a b c", false},
+		{"hello world", false},
+	}
+	for _, tt := range tests {
+		if got := Blocked(tt.text); got != tt.blocked {
+			t.Errorf("Blocked(%q) = %v, want %v", tt.text, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckCompounds(t *testing.T) {
+	compounds := []string{"motherfucker", "bullshit", "fuckyou", "fuckface", "shithead", "dumbass", "bitchass", "shitshow"}
+	for _, text := range compounds {
+		if err := CheckWord(text); err == nil {
+			t.Errorf("CheckWord(%q) accepted a blocked word", text)
+		}
+		if err := CheckPlayerName(text); err == nil {
+			t.Errorf("CheckPlayerName(%q) accepted a blocked name", text)
+		}
+	}
+	for _, text := range []string{"Scunthorpe", "shiitake", "passage"} {
+		if err := CheckWord(text); err != nil {
+			t.Errorf("CheckWord(%q) = %v, want nil", text, err)
+		}
+	}
+}
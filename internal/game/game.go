@@ -13,11 +13,17 @@ type Game struct {
 	AttemptsLeft   int
 	Letters        map[rune]bool
 	GuessedLetters []rune
+	History        []rune // guesses in the order they were made
 	Status         GameStatus
+	Category       string // word bank category the word came from, if any
+	Clue           string // optional hint from a custom word list
 }
 
 type GameStatus int
 
+// Wrong guesses allowed before a game is lost
+const MaxAttempts = 6
+
 const (
 	InProgress GameStatus = iota
 	Won
@@ -48,6 +54,14 @@ func ValidateWord(word string) bool {
 	return true
 }
 
+// NewGameInCategory starts a game on a word picked from a word bank category,
+// so guessers can be told what kind of word they're looking for
+func NewGameInCategory(word string, category string) Game {
+	g := NewGame(word)
+	g.Category = category
+	return g
+}
+
 func NewGame(word string) Game {
 	revealed := make([]rune, len(word))
 	for i := range revealed {
@@ -57,9 +71,10 @@ func NewGame(word string) Game {
 	return Game{
 		Word:           strings.ToLower(word),
 		Revealed:       revealed,
-		AttemptsLeft:   6,
+		AttemptsLeft:   MaxAttempts,
 		Letters:        setLetters(),
 		GuessedLetters: make([]rune, 0, 26),
+		History:        make([]rune, 0, 26),
 		Status:         InProgress,
 	}
 }
@@ -90,6 +105,7 @@ func (g *Game) Guess(letter rune) bool {
 
 	g.Letters[letter] = true
 	g.GuessedLetters = append(g.GuessedLetters, letter)
+	g.History = append(g.History, letter)
 	sort.Slice(g.GuessedLetters, func(i, j int) bool {
 		return g.GuessedLetters[i] < g.GuessedLetters[j]
 	})
@@ -130,6 +146,18 @@ func (g *Game) checkGameStatus() {
 	}
 }
 
+// RevealedCount returns how many letters of the word were uncovered by guesses.
+// It ignores letters shown only because the game was lost.
+func (g *Game) RevealedCount() int {
+	count := 0
+	for _, c := range g.Word {
+		if g.Letters[c] {
+			count++
+		}
+	}
+	return count
+}
+
 func (g *Game) WinOrLost() bool {
 	if g.Status == Won {
 		log.Print("You win")
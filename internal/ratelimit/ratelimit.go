@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is how fast something may happen: Rate times per second on average,
+// with up to Burst at once
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute is a limit of n a minute with bursts of up to n
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+// Bucket is a token bucket. It starts full and refills at the limit's rate.
+type Bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(limit Limit) *Bucket {
+	return &Bucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// Allow takes a token if there is one. Not safe for concurrent use.
+func (b *Bucket) Allow() bool {
+	now := time.Now()
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *Bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.last = now
+}
+
+// How many Allow calls between sweeps for idle buckets
+const sweepEvery = 1024
+
+// Limiter keeps a bucket per key, e.g. per player or per IP
+type Limiter struct {
+	mu      sync.Mutex
+	limit   Limit
+	buckets map[string]*Bucket
+	calls   int
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{limit: limit, buckets: make(map[string]*Bucket)}
+}
+
+// Allow takes a token from key's bucket
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep()
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = NewBucket(l.limit)
+		l.buckets[key] = b
+	}
+	return b.Allow()
+}
+
+// Forgets buckets that have refilled, they'd start out full again anyway
+func (l *Limiter) sweep() {
+	now := time.Now()
+	for key, b := range l.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// TrustProxy makes IP use the address our proxy added to X-Forwarded-For.
+// Only turn it on behind a proxy, otherwise clients can claim any address.
+var TrustProxy bool
+
+// IP is the address a request came from
+func IP(r *http.Request) string {
+	if TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// earlier entries come from the client, the last from our proxy
+			addrs := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware turns away requests whose key, e.g. IP, has used up its bucket
+func (l *Limiter) Middleware(key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !l.Allow(key(r)) {
+				http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
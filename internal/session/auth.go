@@ -0,0 +1,79 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// IssueToken creates the secret a player or spectator proves who they are with
+// when they connect. Player IDs are shown to everyone in the lobby, so on their
+// own they can't be trusted.
+func IssueToken(lobbyID, playerID string) (string, error) {
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
+		return "", errors.New("lobby not found")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	lobby.ConnLock.Lock()
+	if lobby.tokens == nil {
+		lobby.tokens = make(map[string]string)
+	}
+	lobby.tokens[token] = playerID
+	lobby.ConnLock.Unlock()
+	return token, nil
+}
+
+// RevokeTokens ends every session a player has on a lobby, e.g. once they leave their seat
+func RevokeTokens(lobbyID, playerID string) {
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
+		return
+	}
+
+	lobby.ConnLock.Lock()
+	defer lobby.ConnLock.Unlock()
+	for token, id := range lobby.tokens {
+		if id == playerID {
+			delete(lobby.tokens, token)
+		}
+	}
+}
+
+// Authenticate returns who a token was issued to, as long as they are still
+// seated in the lobby or watching it
+func Authenticate(lobbyID, token string) (playerID string, ok bool) {
+	if token == "" {
+		return "", false
+	}
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
+		return "", false
+	}
+
+	lobby.ConnLock.Lock()
+	playerID, ok = lobby.tokens[token]
+	spectator := ok && lobby.IsSpectator(playerID)
+	lobby.ConnLock.Unlock()
+	if !ok {
+		return "", false
+	}
+	if spectator {
+		return playerID, true
+	}
+
+	// seats change under StateLock, which comes before ConnLock
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
+	m := lobby.Member(playerID)
+	if playerID != lobby.Player1ID && playerID != lobby.Player2ID && (m == nil || m.Left) {
+		return "", false
+	}
+	return playerID, true
+}
@@ -0,0 +1,241 @@
+package session
+
+import "github.com/Kalani-Kawaguchi/Hangman/internal/game"
+
+// Phase is where a player is in the current game, as far as their screen is concerned
+type Phase string
+
+const (
+	PhaseWaitingForPlayer   Phase = "waiting_for_player"   // nobody in the seat yet
+	PhasePickingWord        Phase = "picking_word"         // setting a word for someone else
+	PhaseGettingReady       Phase = "getting_ready"        // server picked modes, not ready yet
+	PhaseWaitingForOpponent Phase = "waiting_for_opponent" // done, the other side isn't
+	PhaseReady              Phase = "ready"                // pressed ready in co-op or a race, the other side hasn't
+	PhaseWaitingForHost     Phase = "waiting_for_host"
+	PhaseWaitingForTurn     Phase = "waiting_for_turn"
+	PhaseGuessing           Phase = "guessing"
+	PhaseWatching           Phase = "watching" // the turn based setter while the others guess
+	PhaseWon                Phase = "won"
+	PhaseLost               Phase = "lost"
+	PhaseWantsRematch       Phase = "wants_rematch"
+)
+
+// Prompt a player sees for their own phase
+var prompts = map[Phase]string{
+	PhaseWaitingForPlayer:   "Waiting for an opponent to join.",
+	PhasePickingWord:        "Enter a word for your opponent to guess:",
+	PhaseGettingReady:       "Press ready when you want to start.",
+	PhaseWaitingForOpponent: "waiting for opponent word",
+	PhaseReady:              "Ready. Waiting for your opponent...",
+	PhaseWaitingForHost:     "Waiting for the host to start.",
+	PhaseWaitingForTurn:     "Waiting for your turn.",
+	PhaseGuessing:           "Type a letter to guess.",
+	PhaseWatching:           "Watch them guess your word.",
+	PhaseWon:                "You win!",
+	PhaseLost:               "Game Over! The word was:",
+	PhaseWantsRematch:       "Waiting for your opponent to play again.",
+}
+
+// Prompt a player sees about their opponent's phase
+var opponentPrompts = map[Phase]string{
+	PhaseWaitingForPlayer:   "Waiting for an opponent to join.",
+	PhasePickingWord:        "Picking a word.",
+	PhaseGettingReady:       "Getting ready.",
+	PhaseWaitingForOpponent: "Word set. Waiting for you...",
+	PhaseReady:              "Ready. Waiting for you...",
+	PhaseGuessing:           "",
+	PhaseWon:                "Opponent won!",
+	PhaseLost:               "Game Over! The word was:",
+	PhaseWantsRematch:       "Wants to play again.",
+}
+
+// Instruction is a phase and the prompt that goes with it
+type Instruction struct {
+	Phase  Phase  `json:"phase"`
+	Prompt string `json:"prompt"`
+}
+
+// InstructionFor works out a player's phase from the lobby and game state.
+// Players who aren't seated get an empty instruction.
+func (l *Lobby) InstructionFor(playerID string) Instruction {
+	if l.IsMultiplayer() {
+		return l.memberInstruction(playerID)
+	}
+	if seat := l.seatOf(playerID); seat != 0 {
+		return l.SeatInstruction(seat)
+	}
+	return Instruction{}
+}
+
+// OpponentInstructionFor describes the other seat's phase to a player in a two
+// seat lobby. Multiplayer lobbies have no single opponent.
+func (l *Lobby) OpponentInstructionFor(playerID string) Instruction {
+	if l.IsMultiplayer() {
+		return Instruction{}
+	}
+	if seat := l.seatOf(playerID); seat != 0 {
+		return l.OpponentInstruction(seat)
+	}
+	return Instruction{}
+}
+
+// SeatInstruction is the instruction for seat 1 or 2 of a two seat lobby
+func (l *Lobby) SeatInstruction(seat int) Instruction {
+	phase := l.seatPhase(seat)
+	return Instruction{Phase: phase, Prompt: prompts[phase]}
+}
+
+// OpponentInstruction is what seat 1 or 2 is told about the other seat
+func (l *Lobby) OpponentInstruction(seat int) Instruction {
+	phase := l.seatPhase(3 - seat)
+	return Instruction{Phase: phase, Prompt: opponentPrompts[phase]}
+}
+
+func (l *Lobby) seatOf(playerID string) int {
+	switch {
+	case playerID == "":
+		return 0
+	case playerID == l.Player1ID:
+		return 1
+	case playerID == l.Player2ID:
+		return 2
+	}
+	return 0
+}
+
+// The last result once an ended game's boards have been cleared for the next
+// one. Instructions sent with the end broadcast still go by the live boards.
+func (l *Lobby) cleared() *Result {
+	if l.State != StateEnded || l.LastResult == nil || l.Game1.Word != "" || l.Game2.Word != "" {
+		return nil
+	}
+	return l.LastResult
+}
+
+// Boards instructions go by
+func (l *Lobby) boards() (game1, game2 *game.Game) {
+	if result := l.cleared(); result != nil {
+		return &result.Game1, &result.Game2
+	}
+	return &l.Game1, &l.Game2
+}
+
+func (l *Lobby) seatPhase(seat int) Phase {
+	game1, game2 := l.boards()
+	exists, ready, restarted, board := l.Player1Exists, l.Game1Ready, l.Player1Restarted, game2
+	if seat == 2 {
+		exists, ready, restarted, board = l.Player2Exists, l.Game2Ready, l.Player2Restarted, game1
+	}
+	if l.Mode == ModeCoop {
+		board = game1
+	}
+	if !exists {
+		return PhaseWaitingForPlayer
+	}
+
+	switch l.State {
+	case StateWaiting:
+		if l.Mode == ModeCoop || l.Mode == ModeRace {
+			if ready {
+				return PhaseReady
+			}
+			return PhaseGettingReady
+		}
+		if ready {
+			return PhaseWaitingForOpponent
+		}
+		return PhasePickingWord
+	case StateEnded:
+		if restarted {
+			return PhaseWantsRematch
+		}
+		if l.Mode == ModeRace {
+			winner, _ := l.RaceWinner()
+			if result := l.cleared(); result != nil {
+				winner = result.RaceWinner
+			}
+			if winner == seat {
+				return PhaseWon
+			}
+			return PhaseLost
+		}
+	}
+	return boardPhase(board, l.State == StateEnded)
+}
+
+// Phase of a guesser going by their board. A board still in progress when the
+// game has ended was never solved.
+func boardPhase(board *game.Game, ended bool) Phase {
+	switch {
+	case board.Status == game.Won:
+		return PhaseWon
+	case board.Status == game.Lost || ended:
+		return PhaseLost
+	}
+	return PhaseGuessing
+}
+
+func (l *Lobby) memberInstruction(playerID string) Instruction {
+	m := l.Member(playerID)
+	if m == nil {
+		return Instruction{}
+	}
+
+	game1, game2 := l.boards()
+	var phase Phase
+	switch l.Mode {
+	case ModeRoyale:
+		switch {
+		case l.State == StateWaiting:
+			phase = PhaseWaitingForHost
+		case m.Eliminated:
+			phase = PhaseLost
+		case l.State == StateEnded:
+			phase = PhaseWon
+		default:
+			phase = boardPhase(&m.Game, false)
+		}
+	case ModeTeams:
+		teamReady, board := l.Game1Ready, game2
+		if m.Team == 2 {
+			teamReady, board = l.Game2Ready, game1
+		}
+		switch {
+		case l.State == StateWaiting && teamReady:
+			phase = PhaseWaitingForOpponent
+		case l.State == StateWaiting:
+			phase = PhasePickingWord
+		default:
+			phase = boardPhase(board, l.State == StateEnded)
+			if phase == PhaseGuessing {
+				if turn := l.TeamTurn(m.Team); turn != nil && turn.ID != m.ID {
+					phase = PhaseWaitingForTurn
+				}
+			}
+		}
+	case ModeTurns:
+		switch {
+		case playerID == l.SetterID && l.State == StateWaiting:
+			phase = PhasePickingWord
+		case playerID == l.SetterID:
+			phase = PhaseWatching
+		case l.State == StateWaiting:
+			phase = PhaseWaitingForOpponent
+		case m.Eliminated:
+			phase = PhaseLost
+		default:
+			phase = boardPhase(game1, l.State == StateEnded)
+			if phase == PhaseGuessing {
+				if turn := l.CurrentGuesser(); turn != nil && turn.ID != m.ID {
+					phase = PhaseWaitingForTurn
+				}
+			}
+		}
+	}
+
+	// the host is the one who starts a battle royale
+	if phase == PhaseWaitingForHost && playerID == l.Player1ID {
+		return Instruction{Phase: phase, Prompt: "Start the game when everyone has joined."}
+	}
+	return Instruction{Phase: phase, Prompt: prompts[phase]}
+}
@@ -4,10 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Kalani-Kawaguchi/Hangman/internal/bot"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/dictionary"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/filter"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/wordbank"
 	"github.com/gorilla/websocket"
 )
 
@@ -20,32 +26,94 @@ const (
 	StateEnded   LobbyState = "ended"
 )
 
+type GameMode string
+
+const (
+	ModeClassic GameMode = "classic" // each player sets a word for the other
+	ModeCoop    GameMode = "coop"    // both players guess one shared word
+	ModeRace    GameMode = "race"    // both players race to solve copies of the same word
+	ModeRoyale  GameMode = "royale"  // everyone guesses the same words, a lost word eliminates you
+	ModeTeams   GameMode = "teams"   // two teams set a word for each other and take turns guessing
+	ModeTurns   GameMode = "turns"   // one setter, everyone else guesses the same word in rotation
+)
+
 type Lobby struct {
-	ID                    string `json:"id"`
-	Name                  string `json:"name"`
-	Player1               string `json:"player1"`
-	Player2               string `json:"player2,omitempty"`
-	Player1ID             string
-	Player2ID             string
-	State                 LobbyState `json:"state"`
-	Created               time.Time  `json:"created"`
-	Game1                 game.Game
-	Game2                 game.Game
-	Game1Ready            bool
-	Game2Ready            bool
-	Clients               map[*websocket.Conn]string // active WebSocket clients. Client: PlayerID
-	ConnLock              sync.Mutex                 // protects Clients map
-	Player1Restarted      bool
-	Player2Restarted      bool
-	PlayerCount           string
-	Player1Instruction    string
-	Player2Instruction    string
-	Player1OppInstruction string
-	Player2OppInstruction string
-	Player1Exists         bool
-	Player2Exists         bool
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Player1            string `json:"player1"`
+	Player2            string `json:"player2,omitempty"`
+	Player1ID          string
+	Player2ID          string
+	State              LobbyState `json:"state"`
+	Created            time.Time  `json:"created"`
+	Game1              game.Game
+	Game2              game.Game
+	Game1Ready         bool
+	Game2Ready         bool
+	Clients            map[*websocket.Conn]string // active WebSocket clients. Client: PlayerID
+	ConnLock           sync.Mutex                 // protects Clients map
+	StateLock          sync.Mutex                 // serializes commands, the bot and anything else changing the game, taken before ConnLock
+	Player1Restarted   bool
+	Player2Restarted   bool
+	PlayerCount        string
+	Player1Exists      bool
+	Player2Exists      bool
+	Spectators         map[string]string // read-only viewers. SpectatorID: Name, protected by ConnLock
+	tokens             map[string]string // session token: player or spectator ID, protected by ConnLock
+	BestOf             int               // rounds in the series, 1 means a single game
+	Round              int
+	Player1Score       int
+	Player2Score       int
+	Mode               GameMode `json:"mode"`
+	CoopWord           string   // word set by a third party for the next co-op round
+	Player1CoopGuesses []rune   // letters player1 guessed on the shared co-op game
+	Player2CoopGuesses []rune
+	LastGuessBy        string    // "1" or "2", whoever made the latest co-op guess
+	Members            []*Member // every seated player in modes with more than two players, host first
+	LastEliminated     string    // ID of the most recently eliminated member
+	Team1Turn          int       // index of the team 1 member whose turn it is to guess
+	Team2Turn          int
+	SetterID           string           // member who sets the word in turn based mode
+	TurnIndex          int              // position in the guesser rotation in turn based mode
+	LastSolver         string           // member who solved the latest turn based word
+	BotDifficulty      string           // set when the bot fills the Player2 seat
+	BotGame            int              // counts the bot's games, so a loop left from an earlier one stops
+	Practice           bool             // players can ask the solver for hints
+	Language           string           // dictionary used for word rules and server picked words
+	DictionaryWords    bool             // submitted words must be in the lobby's dictionary
+	WordBank           string           // bank server picked and random words come from
+	CustomWords        []wordbank.Entry // host uploaded list, used instead of WordBank when set
+	MinDifficulty      string           // easiest band a submitted word may fall in, empty for any
+	MaxDifficulty      string           // hardest band a submitted word may fall in, empty for any
+	MaxPlayers         int
+	LastResult         *Result // the game that last ended, nil until one has
+}
+
+// Result is a finished game, kept after its boards are cleared for the next one
+type Result struct {
+	Game1      game.Game
+	Game2      game.Game
+	RaceWinner int // seat that won a race, 0 for a draw or another mode
+}
+
+// Settings chosen by the host when creating a lobby
+type LobbyOptions struct {
+	BestOf          int      `json:"best_of"`
+	Mode            GameMode `json:"mode"`
+	MaxPlayers      int      `json:"max_players"`
+	Bot             bool     `json:"bot"`
+	BotDifficulty   string   `json:"bot_difficulty"`
+	Practice        bool     `json:"practice"`
+	Language        string   `json:"language"`
+	DictionaryWords bool     `json:"dictionary_words"`
+	WordBank        string   `json:"word_bank"`
+	MinDifficulty   string   `json:"min_difficulty"`
+	MaxDifficulty   string   `json:"max_difficulty"`
 }
 
+// Name shown for the bot in the Player2 seat
+const BotName = "Bot"
+
 // Might move this somewhere else
 type WordRequest struct {
 	Word string `json:"word"`
@@ -66,6 +134,14 @@ type LobbySummary struct {
 	Player2OppInstruction string     `json:"player2OppInstruction"`
 	Player1RevealedWord   []rune     `json:"player1RevealedWord"`
 	Player2RevealedWord   []rune     `json:"player2RevealedWord"`
+	SpectatorCount        int        `json:"spectatorCount"`
+	BestOf                int        `json:"bestOf"`
+	Round                 int        `json:"round"`
+	Player1Score          int        `json:"player1Score"`
+	Player2Score          int        `json:"player2Score"`
+	Mode                  GameMode   `json:"mode"`
+	MaxPlayers            int        `json:"maxPlayers"`
+	Practice              bool       `json:"practice"`
 }
 
 // Thread-safe map to store active lobbies
@@ -74,41 +150,152 @@ var (
 	lobbiesMu sync.Mutex
 )
 
+// Validate checks the host supplied options before a lobby is created
+func (o LobbyOptions) Validate() error {
+	switch o.BestOf {
+	case 0, 1, 3, 5, 7:
+	default:
+		return errors.New("best_of must be 1, 3, 5 or 7")
+	}
+	switch o.Mode {
+	case "", ModeClassic, ModeCoop, ModeRace, ModeRoyale, ModeTeams, ModeTurns:
+	default:
+		return fmt.Errorf("unknown game mode %q", o.Mode)
+	}
+	// 0 leaves the mode's default
+	if o.MaxPlayers != 0 && (o.MaxPlayers < 2 || o.MaxPlayers > maxLobbyPlayers) {
+		return fmt.Errorf("max_players must be between 2 and %d", maxLobbyPlayers)
+	}
+	if o.Language != "" || o.DictionaryWords {
+		language := o.Language
+		if language == "" {
+			language = dictionary.DefaultLanguage
+		}
+		if _, ok := dictionary.Get(language); !ok {
+			return fmt.Errorf("no dictionary loaded for language %q", language)
+		}
+	}
+	if o.WordBank != "" {
+		if _, ok := wordbank.Get(o.WordBank); !ok {
+			return fmt.Errorf("unknown word bank %q", o.WordBank)
+		}
+	}
+	for _, band := range []string{o.MinDifficulty, o.MaxDifficulty} {
+		if band != "" && solver.BandIndex(band) < 0 {
+			return errors.New("difficulty bands must be easy, medium or hard")
+		}
+	}
+	if o.MinDifficulty != "" && o.MaxDifficulty != "" && solver.BandIndex(o.MinDifficulty) > solver.BandIndex(o.MaxDifficulty) {
+		return errors.New("min_difficulty is harder than max_difficulty")
+	}
+	if o.Bot {
+		if o.Mode != "" && o.Mode != ModeClassic {
+			return errors.New("the bot only plays classic mode")
+		}
+		if o.BotDifficulty != "" && !bot.ValidDifficulty(bot.Difficulty(o.BotDifficulty)) {
+			return errors.New("bot_difficulty must be easy, medium or hard")
+		}
+	}
+	return nil
+}
+
 // CreateLobby initializes a new lobby and returns it
-func CreateLobby(name string) *Lobby {
+func CreateLobby(name string, opts LobbyOptions) (*Lobby, error) {
+	if err := filter.CheckLobbyName(name); err != nil {
+		return nil, err
+	}
+
 	lobbiesMu.Lock()
 	defer lobbiesMu.Unlock()
 
+	bestOf := opts.BestOf
+	if bestOf == 0 {
+		bestOf = 1
+	}
+	mode := opts.Mode
+	if mode == "" {
+		mode = ModeClassic
+	}
+	maxPlayers := 2
+	if mode == ModeRoyale {
+		maxPlayers = opts.MaxPlayers
+		if maxPlayers < 2 {
+			maxPlayers = maxLobbyPlayers
+		}
+	} else if mode == ModeTeams {
+		maxPlayers = 4
+	} else if mode == ModeTurns {
+		maxPlayers = opts.MaxPlayers
+		if maxPlayers < 2 {
+			maxPlayers = defaultTurnsPlayers
+		}
+	}
+
 	id := GenerateID()
 	lobby := &Lobby{
-		ID:                    id,
-		Name:                  name,
-		State:                 StateWaiting,
-		Created:               time.Now(),
-		Game1Ready:            false,
-		Game2Ready:            false,
-		Clients:               make(map[*websocket.Conn]string),
-		PlayerCount:           "1",
-		Player1Instruction:    "Enter a word for your opponent to guess:",
-		Player2Instruction:    "Enter a word for your opponent to guess:",
-		Player1OppInstruction: "Picking a word.",
-		Player2OppInstruction: "Picking a word.",
+		ID:              id,
+		Name:            name,
+		State:           StateWaiting,
+		Created:         time.Now(),
+		Game1Ready:      false,
+		Game2Ready:      false,
+		Clients:         make(map[*websocket.Conn]string),
+		Spectators:      make(map[string]string),
+		PlayerCount:     "1",
+		BestOf:          bestOf,
+		Round:           1,
+		Mode:            mode,
+		MaxPlayers:      maxPlayers,
+		Practice:        opts.Practice,
+		Language:        opts.Language,
+		DictionaryWords: opts.DictionaryWords,
+		WordBank:        opts.WordBank,
+		MinDifficulty:   opts.MinDifficulty,
+		MaxDifficulty:   opts.MaxDifficulty,
+	}
+	if lobby.WordBank == "" {
+		lobby.WordBank = wordbank.DefaultBank
+	}
+	if lobby.Language == "" {
+		lobby.Language = dictionary.DefaultLanguage
+	}
+
+	if opts.Bot {
+		lobby.BotDifficulty = opts.BotDifficulty
+		if lobby.BotDifficulty == "" {
+			lobby.BotDifficulty = string(bot.Medium)
+		}
+		lobby.Player2 = BotName
+		lobby.Player2ID = "BOT" + GenerateID()
+		lobby.Player2Exists = true
+		lobby.PlayerCount = "2"
+		if err := lobby.BotPickWord(); err != nil {
+			return nil, err
+		}
 	}
 
 	lobbies[id] = lobby
 
-	return lobby
+	return lobby, nil
 }
 
 // JoinLobby assigns a player to an existing lobby
 func JoinLobby(lobbyID, playerName string, playerID string) (*Lobby, error) {
-	lobbiesMu.Lock()
-	defer lobbiesMu.Unlock()
-	lobby, exists := lobbies[lobbyID]
-	if !exists {
+	if err := filter.CheckPlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
 		return nil, errors.New("lobby not found")
 	}
 
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
+	if lobby.IsMultiplayer() {
+		return lobby, lobby.addMember(playerName, playerID)
+	}
+
 	// Check which Lobby player to assign to
 	if lobby.Player1 == "" {
 		lobby.Player1 = playerName
@@ -128,6 +315,295 @@ func JoinLobby(lobbyID, playerName string, playerID string) (*Lobby, error) {
 	return lobby, nil
 }
 
+// AddSpectator registers a read-only viewer with an existing lobby.
+// Spectators can join at any point, even while a game is in progress.
+func AddSpectator(lobbyID, spectatorName string, spectatorID string) (*Lobby, error) {
+	if err := filter.CheckPlayerName(spectatorName); err != nil {
+		return nil, err
+	}
+
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
+		return nil, errors.New("lobby not found")
+	}
+
+	lobby.ConnLock.Lock()
+	lobby.Spectators[spectatorID] = spectatorName
+	lobby.ConnLock.Unlock()
+
+	return lobby, nil
+}
+
+// RemoveSpectator drops a viewer from the lobby, e.g. once their connection closes
+func RemoveSpectator(lobbyID, spectatorID string) {
+	lobby, err := GetLobby(lobbyID)
+	if err != nil {
+		return
+	}
+
+	lobby.ConnLock.Lock()
+	delete(lobby.Spectators, spectatorID)
+	lobby.ConnLock.Unlock()
+}
+
+// SpectatorCount is the number of spectators watching the lobby
+func (l *Lobby) SpectatorCount() int {
+	l.ConnLock.Lock()
+	defer l.ConnLock.Unlock()
+	return len(l.Spectators)
+}
+
+// IsSpectator reports whether the given ID belongs to a spectator rather than a player.
+// Callers must hold ConnLock.
+func (l *Lobby) IsSpectator(id string) bool {
+	_, ok := l.Spectators[id]
+	return ok
+}
+
+// HasBot reports whether the bot is sitting in the Player2 seat
+func (l *Lobby) HasBot() bool {
+	return l.BotDifficulty != ""
+}
+
+// Dictionary returns the word list for the lobby's language, or nil if none is loaded
+func (l *Lobby) Dictionary() *dictionary.Dictionary {
+	d, ok := dictionary.Get(l.Language)
+	if !ok {
+		return nil
+	}
+	return d
+}
+
+// CheckWord applies the lobby's rules to a submitted word and explains why it
+// was rejected, so the reason can be sent back to the setter
+func (l *Lobby) CheckWord(word string) error {
+	if err := filter.CheckWord(word); err != nil {
+		return err
+	}
+	if !game.ValidateWord(word) {
+		return errors.New("words can only contain english letters")
+	}
+	if l.DictionaryWords {
+		d := l.Dictionary()
+		if d == nil || !d.Contains(word) {
+			return fmt.Errorf("%q is not in the %s dictionary", strings.ToLower(word), l.Language)
+		}
+	}
+	if l.MinDifficulty != "" || l.MaxDifficulty != "" {
+		band := solver.BandIndex(l.EstimateWord(word).Band)
+		if l.MinDifficulty != "" && band < solver.BandIndex(l.MinDifficulty) {
+			return fmt.Errorf("%q is too easy, words must be at least %s", strings.ToLower(word), l.MinDifficulty)
+		}
+		if l.MaxDifficulty != "" && band > solver.BandIndex(l.MaxDifficulty) {
+			return fmt.Errorf("%q is too hard, words must be at most %s", strings.ToLower(word), l.MaxDifficulty)
+		}
+	}
+	return nil
+}
+
+// SolverWords is the word list the solver works from in this lobby
+func (l *Lobby) SolverWords() []string {
+	if d := l.Dictionary(); d != nil {
+		return d.Words()
+	}
+	return wordbank.Words
+}
+
+// EstimateWord rates how hard word would be for the other side to guess
+func (l *Lobby) EstimateWord(word string) solver.Estimate {
+	return solver.EstimateWord(word, l.SolverWords())
+}
+
+// PickEntry draws a random word from the lobby's word bank, optionally
+// limited to one difficulty
+func (l *Lobby) PickEntry(difficulty string) (wordbank.Entry, error) {
+	if len(l.CustomWords) > 0 {
+		entry, ok := wordbank.RandomEntry(l.CustomWords, difficulty)
+		if !ok {
+			return wordbank.Entry{}, fmt.Errorf("no %s words in the custom word list", difficulty)
+		}
+		return entry, nil
+	}
+
+	bank, ok := wordbank.Get(l.WordBank)
+	if !ok {
+		if bank, ok = wordbank.Get(wordbank.DefaultBank); !ok {
+			return wordbank.Entry{}, errors.New("no word banks are loaded")
+		}
+	}
+	entry, ok := bank.RandomByDifficulty(difficulty)
+	if !ok {
+		return wordbank.Entry{}, fmt.Errorf("no %s words in the %s word bank", difficulty, bank.Name)
+	}
+	return entry, nil
+}
+
+// SetCustomWords runs a host's uploaded list through the content filter and
+// stores what passes for random word assignment. Entries without a difficulty
+// get the estimated one. Entries that fail are returned with the reason, and a
+// list where nothing passes leaves the previous one in place.
+func (l *Lobby) SetCustomWords(entries []wordbank.Entry) (accepted int, rejected []string) {
+	var kept []wordbank.Entry
+	for _, entry := range entries {
+		if err := filter.CheckWord(entry.Word); err != nil {
+			rejected = append(rejected, entry.Word+": "+err.Error())
+			continue
+		}
+		if filter.Blocked(entry.Clue) || filter.Blocked(entry.Category) {
+			rejected = append(rejected, entry.Word+": clue or category contains blocked language")
+			continue
+		}
+		if entry.Difficulty == "" {
+			entry.Difficulty = l.EstimateWord(entry.Word).Band
+		}
+		kept = append(kept, entry)
+	}
+
+	if len(kept) > 0 {
+		l.StateLock.Lock()
+		l.CustomWords = kept
+		l.StateLock.Unlock()
+	}
+	return len(kept), rejected
+}
+
+// BotPickWord has the bot set Game2 for the human in the Player1 seat. The
+// bot stays unready when there's no word to pick.
+func (l *Lobby) BotPickWord() error {
+	entry, err := l.PickEntry("")
+	if err != nil {
+		return err
+	}
+	l.Game2 = entry.NewGame()
+	l.Game2Ready = true
+	return nil
+}
+
+// GameFor returns the game the given player is guessing on, or nil for
+// spectators and unknown IDs
+func (l *Lobby) GameFor(playerID string) *game.Game {
+	switch l.Mode {
+	case ModeCoop:
+		if playerID == l.Player1ID || playerID == l.Player2ID {
+			return &l.Game1
+		}
+	case ModeTurns:
+		if m := l.Member(playerID); m != nil && playerID != l.SetterID {
+			return &l.Game1
+		}
+	case ModeRoyale:
+		if m := l.Member(playerID); m != nil {
+			return &m.Game
+		}
+	case ModeTeams:
+		if m := l.Member(playerID); m != nil {
+			if m.Team == 1 {
+				return &l.Game2
+			}
+			return &l.Game1
+		}
+	default:
+		if playerID == l.Player1ID {
+			return &l.Game2
+		} else if playerID == l.Player2ID {
+			return &l.Game1
+		}
+	}
+	return nil
+}
+
+// IsSeries reports whether the lobby is playing more than a single round.
+// Series are scored head to head, so only classic lobbies keep score.
+func (l *Lobby) IsSeries() bool {
+	return l.BestOf > 1 && l.Mode == ModeClassic
+}
+
+// ScoreRound awards the finished round to a player and returns 1 or 2 for the winner,
+// or 0 for a draw. A player wins the round by solving their word when the opponent
+// didn't; if both solved it, the one with more attempts left takes it.
+func (l *Lobby) ScoreRound() int {
+	p1Won := l.Game2.Status == game.Won
+	p2Won := l.Game1.Status == game.Won
+
+	winner := 0
+	switch {
+	case p1Won && !p2Won:
+		winner = 1
+	case p2Won && !p1Won:
+		winner = 2
+	case p1Won && p2Won && l.Game2.AttemptsLeft > l.Game1.AttemptsLeft:
+		winner = 1
+	case p1Won && p2Won && l.Game1.AttemptsLeft > l.Game2.AttemptsLeft:
+		winner = 2
+	}
+
+	if winner == 1 {
+		l.Player1Score++
+	} else if winner == 2 {
+		l.Player2Score++
+	}
+	return winner
+}
+
+// RaceWinner decides a race mode round. The first player to solve the word wins outright;
+// if both run out of attempts the one with more letters revealed wins. over is false
+// while the race is still undecided, and winner is 0 for a draw.
+func (l *Lobby) RaceWinner() (winner int, over bool) {
+	if l.Game2.Status == game.Won {
+		return 1, true
+	}
+	if l.Game1.Status == game.Won {
+		return 2, true
+	}
+
+	if l.Player2 == "" {
+		return 0, l.Game2.Status == game.Lost
+	}
+	if l.Game1.Status != game.Lost || l.Game2.Status != game.Lost {
+		return 0, false
+	}
+
+	p1Revealed := l.Game2.RevealedCount()
+	p2Revealed := l.Game1.RevealedCount()
+	if p1Revealed > p2Revealed {
+		return 1, true
+	}
+	if p2Revealed > p1Revealed {
+		return 2, true
+	}
+	return 0, true
+}
+
+// SeriesOver reports whether the series is decided: a player has won the
+// majority of rounds, or all BestOf rounds have been played. Drawn rounds still
+// count towards BestOf, so a series never runs past it.
+func (l *Lobby) SeriesOver() bool {
+	needed := l.BestOf/2 + 1
+	return l.Player1Score >= needed || l.Player2Score >= needed || l.Round >= l.BestOf
+}
+
+// SeriesWinner returns 1 or 2 for the player with more rounds won once the
+// series is over, or 0 while it is still going or if it ended in a tie
+func (l *Lobby) SeriesWinner() int {
+	if !l.SeriesOver() {
+		return 0
+	}
+	if l.Player1Score > l.Player2Score {
+		return 1
+	}
+	if l.Player2Score > l.Player1Score {
+		return 2
+	}
+	return 0
+}
+
+// ResetSeries clears the round counter and running score for a new series
+func (l *Lobby) ResetSeries() {
+	l.Round = 1
+	l.Player1Score = 0
+	l.Player2Score = 0
+}
+
 // GetLobby returns a pointer to the lobby if it exists
 func GetLobby(lobbyID string) (*Lobby, error) {
 	lobbiesMu.Lock()
@@ -142,27 +618,48 @@ func GetLobby(lobbyID string) (*Lobby, error) {
 }
 
 func GetLobbyList() []LobbySummary {
+	// each summary takes the lobby's StateLock and ConnLock, which come
+	// before lobbiesMu, so the lobbies are collected first
 	lobbiesMu.Lock()
-	defer lobbiesMu.Unlock()
+	all := make(map[string]*Lobby, len(lobbies))
+	for id, lobby := range lobbies {
+		all[id] = lobby
+	}
+	lobbiesMu.Unlock()
 
 	var availableLobbies []LobbySummary
-	for id, lobby := range lobbies {
-		availableLobbies = append(availableLobbies,
-			LobbySummary{ID: id, Name: lobby.Name, State: lobby.State,
-				Player1: lobby.Player1, Player2: lobby.Player2,
-				PlayerCount: lobby.PlayerCount, Player1Exists: lobby.Player1Exists,
-				Player2Exists:         lobby.Player2Exists,
-				Player1Instruction:    lobby.Player1Instruction,
-				Player2Instruction:    lobby.Player2Instruction,
-				Player1OppInstruction: lobby.Player1OppInstruction,
-				Player2OppInstruction: lobby.Player2OppInstruction,
-				Player1RevealedWord:   lobby.Game2.Revealed,
-				Player2RevealedWord:   lobby.Game1.Revealed,
-			})
+	for _, lobby := range all {
+		availableLobbies = append(availableLobbies, lobby.Summary())
 	}
 	return availableLobbies
 }
 
+// Summary is what anyone may see of a lobby, without player IDs or words.
+// It takes the lobby's StateLock.
+func (l *Lobby) Summary() LobbySummary {
+	l.StateLock.Lock()
+	defer l.StateLock.Unlock()
+	return LobbySummary{ID: l.ID, Name: l.Name, State: l.State,
+		Player1: l.Player1, Player2: l.Player2,
+		PlayerCount: l.PlayerCount, Player1Exists: l.Player1Exists,
+		Player2Exists:         l.Player2Exists,
+		Player1Instruction:    l.SeatInstruction(1).Prompt,
+		Player2Instruction:    l.SeatInstruction(2).Prompt,
+		Player1OppInstruction: l.OpponentInstruction(1).Prompt,
+		Player2OppInstruction: l.OpponentInstruction(2).Prompt,
+		Player1RevealedWord:   append([]rune(nil), l.Game2.Revealed...),
+		Player2RevealedWord:   append([]rune(nil), l.Game1.Revealed...),
+		SpectatorCount:        l.SpectatorCount(),
+		BestOf:                l.BestOf,
+		Round:                 l.Round,
+		Player1Score:          l.Player1Score,
+		Player2Score:          l.Player2Score,
+		Mode:                  l.Mode,
+		MaxPlayers:            l.MaxPlayers,
+		Practice:              l.Practice,
+	}
+}
+
 // Helper to generate a random lobby or Player ID
 func GenerateID() string {
 	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
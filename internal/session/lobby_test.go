@@ -0,0 +1,96 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+)
+
+func TestScoreRound(t *testing.T) {
+	// player1 guesses Game2 and player2 guesses Game1
+	tests := []struct {
+		name    string
+		p1, p2  game.GameStatus
+		p1Left  int
+		p2Left  int
+		winner  int
+		p1Score int
+		p2Score int
+	}{
+		{"player1 solves", game.Won, game.Lost, 3, 0, 1, 1, 0},
+		{"player2 solves", game.Lost, game.Won, 0, 1, 2, 0, 1},
+		{"both solve, player1 with more left", game.Won, game.Won, 4, 2, 1, 1, 0},
+		{"both solve, player2 with more left", game.Won, game.Won, 1, 5, 2, 0, 1},
+		{"both solve with the same left", game.Won, game.Won, 3, 3, 0, 0, 0},
+		{"neither solves", game.Lost, game.Lost, 0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		l := &Lobby{
+			Game1: game.Game{Status: tt.p2, AttemptsLeft: tt.p2Left},
+			Game2: game.Game{Status: tt.p1, AttemptsLeft: tt.p1Left},
+		}
+		if got := l.ScoreRound(); got != tt.winner || l.Player1Score != tt.p1Score || l.Player2Score != tt.p2Score {
+			t.Errorf("%s: ScoreRound() = %d with score %d-%d, want %d with %d-%d",
+				tt.name, got, l.Player1Score, l.Player2Score, tt.winner, tt.p1Score, tt.p2Score)
+		}
+	}
+}
+
+func TestSeriesOver(t *testing.T) {
+	tests := []struct {
+		bestOf  int
+		round   int
+		p1Score int
+		p2Score int
+		over    bool
+		winner  int
+	}{
+		{3, 1, 1, 0, false, 0},
+		{3, 2, 2, 0, true, 1},
+		{3, 2, 1, 1, false, 0},
+		{3, 3, 2, 1, true, 1},
+		// a drawn round still counts, so level after every round is a tie
+		{3, 3, 1, 1, true, 0},
+		{3, 3, 0, 0, true, 0},
+		{5, 3, 0, 3, true, 2},
+		{5, 4, 2, 1, false, 0},
+		{1, 1, 0, 1, true, 2},
+	}
+	for _, tt := range tests {
+		l := &Lobby{BestOf: tt.bestOf, Round: tt.round, Player1Score: tt.p1Score, Player2Score: tt.p2Score}
+		if got := l.SeriesOver(); got != tt.over {
+			t.Errorf("best of %d, round %d at %d-%d: SeriesOver() = %v, want %v",
+				tt.bestOf, tt.round, tt.p1Score, tt.p2Score, got, tt.over)
+		}
+		if got := l.SeriesWinner(); got != tt.winner {
+			t.Errorf("best of %d, round %d at %d-%d: SeriesWinner() = %d, want %d",
+				tt.bestOf, tt.round, tt.p1Score, tt.p2Score, got, tt.winner)
+		}
+	}
+}
+
+func TestAllDrawSeries(t *testing.T) {
+	for _, bestOf := range []int{1, 3, 5} {
+		l := &Lobby{BestOf: bestOf}
+		l.ResetSeries()
+		played := 0
+		for played < 10 {
+			l.Game1 = game.Game{Status: game.Lost}
+			l.Game2 = game.Game{Status: game.Lost}
+			if winner := l.ScoreRound(); winner != 0 {
+				t.Fatalf("best of %d: drawn round won by %d", bestOf, winner)
+			}
+			played++
+			if l.SeriesOver() {
+				break
+			}
+			l.Round++
+		}
+		if played != bestOf {
+			t.Errorf("best of %d: series of draws ended after %d rounds", bestOf, played)
+		}
+		if winner := l.SeriesWinner(); winner != 0 {
+			t.Errorf("best of %d: series of draws won by %d, want a tie", bestOf, winner)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package session
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/wordbank"
+)
+
+// Upper bound on seats for modes that support more than two players
+const maxLobbyPlayers = 100
+
+// Seats in a turn based lobby when the host doesn't pick a size
+const defaultTurnsPlayers = 8
+
+// Member is a seated player in a lobby that holds more than two players.
+// Each member plays their own copy of the current word.
+type Member struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Game            game.Game `json:"-"`
+	WordsSolved     int       `json:"wordsSolved"`
+	Eliminated      bool      `json:"eliminated"`
+	EliminatedRound int       `json:"eliminatedRound,omitempty"`
+	Team            int       `json:"team,omitempty"` // 1 or 2 in team mode
+	Left            bool      `json:"left,omitempty"` // left mid game, dropped on restart
+}
+
+// Standing is one row of the battle royale leaderboard
+type Standing struct {
+	Place           int    `json:"place"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	WordsSolved     int    `json:"wordsSolved"`
+	Revealed        string `json:"revealed"`
+	AttemptsLeft    int    `json:"attemptsLeft"`
+	Eliminated      bool   `json:"eliminated"`
+	EliminatedRound int    `json:"eliminatedRound,omitempty"`
+}
+
+// IsMultiplayer reports whether players are tracked in Members instead of
+// the fixed Player1/Player2 seats
+func (l *Lobby) IsMultiplayer() bool {
+	return l.Mode == ModeRoyale || l.Mode == ModeTeams || l.Mode == ModeTurns
+}
+
+// addMember seats a player, the first one becomes the host in Player1.
+// Callers must hold the lobby's StateLock.
+func (l *Lobby) addMember(playerName string, playerID string) error {
+	if len(l.Members) > 0 && l.State != StateWaiting {
+		return errors.New("lobby is busy")
+	}
+	if len(l.Members) >= l.MaxPlayers {
+		return errors.New("lobby already full")
+	}
+
+	if len(l.Members) == 0 {
+		l.Player1 = playerName
+		l.Player1ID = playerID
+		l.Player1Exists = true
+		l.SetterID = playerID
+	}
+	member := &Member{ID: playerID, Name: playerName}
+	if l.Mode == ModeTeams {
+		// fill the smaller team so teams stay balanced
+		member.Team = 1
+		if len(l.TeamMembers(2)) < len(l.TeamMembers(1)) {
+			member.Team = 2
+		}
+	}
+	l.Members = append(l.Members, member)
+	l.PlayerCount = strconv.Itoa(len(l.Members))
+	return nil
+}
+
+// RemoveMember takes a player out of the lobby. Leaving during a game counts as
+// being eliminated so the standings stay intact, and the member is dropped for
+// good when the game restarts.
+func (l *Lobby) RemoveMember(playerID string) bool {
+	for i, m := range l.Members {
+		if m.ID != playerID {
+			continue
+		}
+		if l.State == StatePlaying {
+			l.Eliminate(m)
+			m.Left = true
+		} else {
+			l.Members = append(l.Members[:i], l.Members[i+1:]...)
+			l.PlayerCount = strconv.Itoa(len(l.Members))
+		}
+		return true
+	}
+	return false
+}
+
+// Member returns the seated player with the given ID, or nil. Members who left
+// mid game are still returned until the restart drops them.
+func (l *Lobby) Member(playerID string) *Member {
+	for _, m := range l.Members {
+		if m.ID == playerID {
+			return m
+		}
+	}
+	return nil
+}
+
+// Alive returns the members who haven't been eliminated yet
+func (l *Lobby) Alive() []*Member {
+	var alive []*Member
+	for _, m := range l.Members {
+		if !m.Eliminated {
+			alive = append(alive, m)
+		}
+	}
+	return alive
+}
+
+// Eliminate knocks a member out in the current round
+func (l *Lobby) Eliminate(m *Member) {
+	m.Eliminated = true
+	m.EliminatedRound = l.Round
+}
+
+// StartRoyaleRound gives every surviving member a fresh copy of the word
+func (l *Lobby) StartRoyaleRound(entry wordbank.Entry) {
+	for _, m := range l.Alive() {
+		m.Game = entry.NewGame()
+	}
+	l.State = StatePlaying
+}
+
+// RoundFinished reports whether every surviving member is done with the current word
+func (l *Lobby) RoundFinished() bool {
+	for _, m := range l.Alive() {
+		if m.Game.Status == game.InProgress {
+			return false
+		}
+	}
+	return true
+}
+
+// ResetMembers brings everyone still seated back in for a new game
+func (l *Lobby) ResetMembers() {
+	seated := l.Members[:0]
+	for _, m := range l.Members {
+		if !m.Left {
+			seated = append(seated, m)
+		}
+	}
+	l.Members = seated
+	l.PlayerCount = strconv.Itoa(len(l.Members))
+
+	for _, m := range l.Members {
+		m.Game = game.Game{}
+		m.WordsSolved = 0
+		m.Eliminated = false
+		m.EliminatedRound = 0
+	}
+	l.Round = 1
+	l.Team1Turn = 0
+	l.Team2Turn = 0
+	if l.Mode == ModeTeams {
+		l.balanceTeams()
+	}
+}
+
+// Moves members from the bigger team to the smaller one until they are at
+// most one apart, so players who left don't leave a team short or empty
+func (l *Lobby) balanceTeams() {
+	for {
+		team1, team2 := l.TeamMembers(1), l.TeamMembers(2)
+		switch {
+		case len(team1) > len(team2)+1:
+			team1[len(team1)-1].Team = 2
+		case len(team2) > len(team1)+1:
+			team2[len(team2)-1].Team = 1
+		default:
+			return
+		}
+	}
+}
+
+// TeamMembers returns the members of a team in turn order, skipping anyone who
+// left mid game. Those are dropped by ResetMembers so they never get a turn.
+func (l *Lobby) TeamMembers(team int) []*Member {
+	var members []*Member
+	for _, m := range l.Members {
+		if m.Team == team && !m.Eliminated {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// TeamTurn returns the member of the team whose turn it is to guess, or nil for an empty team
+func (l *Lobby) TeamTurn(team int) *Member {
+	members := l.TeamMembers(team)
+	if len(members) == 0 {
+		return nil
+	}
+	if team == 1 {
+		return members[l.Team1Turn%len(members)]
+	}
+	return members[l.Team2Turn%len(members)]
+}
+
+// AdvanceTeamTurn passes the guess to the next teammate
+func (l *Lobby) AdvanceTeamTurn(team int) {
+	if team == 1 {
+		l.Team1Turn++
+	} else {
+		l.Team2Turn++
+	}
+}
+
+// Standings ranks members: survivors first by words solved, then the eliminated
+// by how long they lasted
+func (l *Lobby) Standings() []Standing {
+	members := make([]*Member, len(l.Members))
+	copy(members, l.Members)
+	sort.SliceStable(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		if a.Eliminated && a.EliminatedRound != b.EliminatedRound {
+			return a.EliminatedRound > b.EliminatedRound
+		}
+		if a.WordsSolved != b.WordsSolved {
+			return a.WordsSolved > b.WordsSolved
+		}
+		return a.Game.RevealedCount() > b.Game.RevealedCount()
+	})
+
+	standings := make([]Standing, 0, len(members))
+	for i, m := range members {
+		standings = append(standings, Standing{
+			Place:           i + 1,
+			ID:              m.ID,
+			Name:            m.Name,
+			WordsSolved:     m.WordsSolved,
+			Revealed:        string(m.Game.Revealed),
+			AttemptsLeft:    m.Game.AttemptsLeft,
+			Eliminated:      m.Eliminated,
+			EliminatedRound: m.EliminatedRound,
+		})
+	}
+	return standings
+}
+
+// Guessers returns the turn based rotation: everyone still seated except the setter
+func (l *Lobby) Guessers() []*Member {
+	var guessers []*Member
+	for _, m := range l.Members {
+		if m.ID != l.SetterID && !m.Eliminated {
+			guessers = append(guessers, m)
+		}
+	}
+	return guessers
+}
+
+// CurrentGuesser returns the member whose turn it is, or nil when nobody can guess
+func (l *Lobby) CurrentGuesser() *Member {
+	guessers := l.Guessers()
+	if len(guessers) == 0 {
+		return nil
+	}
+	return guessers[l.TurnIndex%len(guessers)]
+}
+
+// AdvanceTurn passes the guess to the next player in the rotation
+func (l *Lobby) AdvanceTurn() {
+	l.TurnIndex++
+}
+
+// RotateSetter hands the word to the next seated player after the current setter
+func (l *Lobby) RotateSetter() {
+	var seated []*Member
+	for _, m := range l.Members {
+		if !m.Eliminated {
+			seated = append(seated, m)
+		}
+	}
+	if len(seated) == 0 {
+		return
+	}
+
+	next := seated[0]
+	for i, m := range seated {
+		if m.ID == l.SetterID {
+			next = seated[(i+1)%len(seated)]
+			break
+		}
+	}
+	l.SetterID = next.ID
+}
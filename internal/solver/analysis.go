@@ -0,0 +1,85 @@
+package solver
+
+// GuessRating compares one of the player's guesses against the best letter
+// available at that point in the game
+type GuessRating struct {
+	Letter                string  `json:"letter"`
+	Hit                   bool    `json:"hit"`
+	Rating                string  `json:"rating"` // best, good, fair, poor or unrated
+	Best                  string  `json:"best,omitempty"`
+	Candidates            int     `json:"candidates"`
+	ExpectedRemaining     float64 `json:"expectedRemaining"`
+	BestExpectedRemaining float64 `json:"bestExpectedRemaining"`
+}
+
+// Report is the post-game breakdown of every guess the player made
+type Report struct {
+	Word     string        `json:"word"`
+	Guesses  []GuessRating `json:"guesses"`
+	Accuracy float64       `json:"accuracy"` // share of rated guesses that were the best choice
+}
+
+// Analyze replays a finished game, rating each guess in history against the
+// solver's choice given what the player could see at the time
+func Analyze(word string, history []rune, dict []string) Report {
+	report := Report{Word: word}
+
+	pattern := make([]rune, len(word))
+	for i := range pattern {
+		pattern[i] = '_'
+	}
+	var guessed []rune
+	rated, best := 0, 0
+
+	for _, letter := range history {
+		rating := GuessRating{Letter: string(letter), Rating: "unrated"}
+
+		suggestions := Suggest(string(pattern), guessed, dict)
+		if len(suggestions) > 0 {
+			rating.Candidates = len(Candidates(string(pattern), guessed, dict))
+			rating.Best = suggestions[0].Letter
+			rating.BestExpectedRemaining = suggestions[0].ExpectedRemaining
+			hitProbability := 0.0
+			for _, s := range suggestions {
+				if s.Letter == string(letter) {
+					rating.ExpectedRemaining = s.ExpectedRemaining
+					hitProbability = s.HitProbability
+				}
+			}
+			rating.Rating = rate(rating.ExpectedRemaining, hitProbability, suggestions[0])
+			rated++
+			if rating.Rating == "best" {
+				best++
+			}
+		}
+
+		for i, c := range word {
+			if c == letter {
+				pattern[i] = c
+				rating.Hit = true
+			}
+		}
+		guessed = append(guessed, letter)
+		report.Guesses = append(report.Guesses, rating)
+	}
+
+	if rated > 0 {
+		report.Accuracy = float64(best) / float64(rated)
+	}
+	return report
+}
+
+// Grades a guess by how many candidates it leaves compared to the best letter,
+// and by how much less likely it was to hit
+func rate(expected, hitProbability float64, best Suggestion) string {
+	switch {
+	case expected <= best.ExpectedRemaining && hitProbability >= best.HitProbability:
+		return "best"
+	case expected <= best.ExpectedRemaining*1.5+0.5 && hitProbability >= best.HitProbability-0.25:
+		return "good"
+	case expected <= best.ExpectedRemaining*3+1 && hitProbability >= best.HitProbability-0.5:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
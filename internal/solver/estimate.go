@@ -0,0 +1,128 @@
+package solver
+
+import (
+	"math"
+	"strings"
+)
+
+// Difficulty bands, the one scale used for estimates, word bank tags and the bot
+const (
+	Easy   = "easy"
+	Medium = "medium"
+	Hard   = "hard"
+)
+
+// Difficulty bands an estimate falls into, easiest first
+var Bands = []string{Easy, Medium, Hard}
+
+// Estimate is how hard a word should be to guess, broken down by the things that
+// make it hard
+type Estimate struct {
+	Score          int     `json:"score"` // 0 for trivial up to 100
+	Band           string  `json:"band"`
+	UniqueLetters  int     `json:"uniqueLetters"`
+	LetterRarity   float64 `json:"letterRarity"`   // 0 when every letter is common, 1 when all are rare
+	ExpectedMisses int     `json:"expectedMisses"` // wrong guesses the solver makes before finishing the word
+	Frequency      float64 `json:"frequency"`      // 0 for the most common dictionary word, 1 when not in it
+}
+
+// EstimateWord rates word against dict, which should be ordered from most to
+// least common so a word's position doubles as its frequency
+func EstimateWord(word string, dict []string) Estimate {
+	word = strings.ToLower(word)
+	est := Estimate{Frequency: 1}
+
+	unique := make(map[rune]bool)
+	rarity := 0.0
+	for _, c := range word {
+		if unique[c] {
+			continue
+		}
+		unique[c] = true
+		if i := strings.IndexRune(letterFrequency, c); i >= 0 {
+			rarity += float64(i) / float64(len(letterFrequency)-1)
+		}
+	}
+	est.UniqueLetters = len(unique)
+	if len(unique) > 0 {
+		est.LetterRarity = rarity / float64(len(unique))
+	}
+
+	for i, w := range dict {
+		if w == word {
+			est.Frequency = float64(i) / float64(len(dict))
+			break
+		}
+	}
+
+	est.ExpectedMisses = solverMisses(word, dict)
+
+	// misses matter most, six being a lost game with the default attempts
+	score := 50*math.Min(float64(est.ExpectedMisses)/6, 1) +
+		20*est.LetterRarity +
+		20*est.Frequency +
+		10*(1-math.Min(float64(est.UniqueLetters), 10)/10)
+	est.Score = int(math.Round(score))
+
+	switch {
+	case est.Score < 35:
+		est.Band = Easy
+	case est.Score < 60:
+		est.Band = Medium
+	default:
+		est.Band = Hard
+	}
+	return est
+}
+
+// BandIndex is a band's position in Bands, or -1 if it isn't one
+func BandIndex(band string) int {
+	for i, b := range Bands {
+		if b == band {
+			return i
+		}
+	}
+	return -1
+}
+
+// Plays the word out with the solver's best guesses, falling back to plain
+// letter frequency once nothing in dict fits, and counts the misses
+func solverMisses(word string, dict []string) int {
+	pattern := []rune(strings.Repeat("_", len(word)))
+	var guessed []rune
+	misses := 0
+
+	for strings.ContainsRune(string(pattern), '_') && len(guessed) < len(letterFrequency) {
+		letter, ok := Best(string(pattern), guessed, dict)
+		if !ok {
+			for _, c := range letterFrequency {
+				if !containsRune(guessed, c) {
+					letter = c
+					break
+				}
+			}
+		}
+		guessed = append(guessed, letter)
+
+		hit := false
+		for i, c := range word {
+			if c == letter {
+				pattern[i] = c
+				hit = true
+			}
+		}
+		if !hit {
+			misses++
+		}
+	}
+	return misses
+}
+
+func containsRune(letters []rune, r rune) bool {
+	for _, c := range letters {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
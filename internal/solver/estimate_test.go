@@ -0,0 +1,62 @@
+package solver
+
+import "testing"
+
+func TestEstimateWord(t *testing.T) {
+	tests := []struct {
+		word           string
+		dict           []string
+		score          int
+		band           string
+		uniqueLetters  int
+		expectedMisses int
+		frequency      float64
+	}{
+		// only common letters and the solver's first guess is a hit
+		{"eee", nil, 29, Easy, 1, 0, 1},
+		// with no dictionary the solver falls back to letter frequency: e, t, then a
+		{"a", nil, 47, Medium, 1, 2, 1},
+		{"jazz", nil, 90, Hard, 3, 23, 1},
+		// the solver only misses with o before it can tell cat from cut
+		{"CAT", testDict, 19, Easy, 3, 1, 0},
+		// o hits, then t misses before it can tell dog from cot. Rarer
+		// letters and a less common word make it harder than cat.
+		{"dog", testDict, 38, Medium, 3, 1, 0.75},
+	}
+	for _, tt := range tests {
+		got := EstimateWord(tt.word, tt.dict)
+		if got.Score != tt.score || got.Band != tt.band || got.UniqueLetters != tt.uniqueLetters ||
+			got.ExpectedMisses != tt.expectedMisses || got.Frequency != tt.frequency {
+			t.Errorf("EstimateWord(%q) = %+v, want score %d, band %s, %d unique letters, %d misses, frequency %v",
+				tt.word, got, tt.score, tt.band, tt.uniqueLetters, tt.expectedMisses, tt.frequency)
+		}
+	}
+}
+
+func TestEstimateWordRarity(t *testing.T) {
+	common, rare := EstimateWord("tee", nil), EstimateWord("zzq", nil)
+	if common.LetterRarity >= rare.LetterRarity {
+		t.Errorf("LetterRarity of tee = %v, zzq = %v, want tee lower", common.LetterRarity, rare.LetterRarity)
+	}
+	if rare.LetterRarity > 1 || common.LetterRarity < 0 {
+		t.Errorf("LetterRarity out of range: tee = %v, zzq = %v", common.LetterRarity, rare.LetterRarity)
+	}
+}
+
+func TestBandIndex(t *testing.T) {
+	tests := []struct {
+		band string
+		want int
+	}{
+		{Easy, 0},
+		{Medium, 1},
+		{Hard, 2},
+		{"", -1},
+		{"extreme", -1},
+	}
+	for _, tt := range tests {
+		if got := BandIndex(tt.band); got != tt.want {
+			t.Errorf("BandIndex(%q) = %d, want %d", tt.band, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package solver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Suggestion is a letter worth guessing next, with how likely it is to be in the
+// word and how many candidate words are expected to remain after guessing it
+type Suggestion struct {
+	Letter            string  `json:"letter"`
+	HitProbability    float64 `json:"hitProbability"`
+	ExpectedRemaining float64 `json:"expectedRemaining"`
+}
+
+// English letters from most to least common, used to break ties
+const letterFrequency = "etaoinshrdlcumwfgypbvkjxqz"
+
+// Candidates filters dict down to the words that fit a revealed pattern such as
+// "_a__a_" given the letters guessed so far. Guessed letters missing from the
+// pattern are misses, so words containing them are dropped.
+func Candidates(pattern string, guessed []rune, dict []string) []string {
+	tried := make(map[rune]bool, len(guessed))
+	for _, letter := range guessed {
+		tried[letter] = true
+	}
+
+	var candidates []string
+	for _, word := range dict {
+		if fits(word, []rune(pattern), tried) {
+			candidates = append(candidates, word)
+		}
+	}
+	return candidates
+}
+
+func fits(word string, pattern []rune, tried map[rune]bool) bool {
+	if len(word) != len(pattern) {
+		return false
+	}
+	for i, c := range word {
+		shown := pattern[i]
+		if shown != '_' && shown != c {
+			return false
+		}
+		// a guessed letter would already be showing if it were in this spot
+		if shown == '_' && tried[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// Suggest ranks every unguessed letter by how much it narrows down the candidate
+// words, best first. It returns nil when no dictionary word fits the pattern.
+func Suggest(pattern string, guessed []rune, dict []string) []Suggestion {
+	candidates := Candidates(pattern, guessed, dict)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	tried := make(map[rune]bool, len(guessed))
+	for _, letter := range guessed {
+		tried[letter] = true
+	}
+
+	total := float64(len(candidates))
+	var suggestions []Suggestion
+	for _, letter := range letterFrequency {
+		if tried[letter] {
+			continue
+		}
+
+		// words that would show the letter in the same spots stay together after the guess
+		groups := make(map[string]int)
+		hits := 0
+		for _, word := range candidates {
+			key := positions(word, letter)
+			if key != "" {
+				hits++
+			}
+			groups[key]++
+		}
+
+		expected := 0.0
+		for _, size := range groups {
+			expected += float64(size) * float64(size) / total
+		}
+		suggestions = append(suggestions, Suggestion{
+			Letter:            string(letter),
+			HitProbability:    float64(hits) / total,
+			ExpectedRemaining: expected,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.ExpectedRemaining != b.ExpectedRemaining {
+			return a.ExpectedRemaining < b.ExpectedRemaining
+		}
+		return a.HitProbability > b.HitProbability
+	})
+	return suggestions
+}
+
+// Best returns the top suggested letter, if any word fits
+func Best(pattern string, guessed []rune, dict []string) (rune, bool) {
+	suggestions := Suggest(pattern, guessed, dict)
+	if len(suggestions) == 0 {
+		return 0, false
+	}
+	return rune(suggestions[0].Letter[0]), true
+}
+
+func positions(word string, letter rune) string {
+	var b strings.Builder
+	for i, c := range word {
+		if c == letter {
+			b.WriteString(strconv.Itoa(i))
+			b.WriteByte(',')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package solver
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var testDict = []string{"cat", "cot", "cut", "dog"}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		pattern string
+		guessed string
+		want    []string
+	}{
+		{"___", "", []string{"cat", "cot", "cut", "dog"}},
+		{"c_t", "ct", []string{"cat", "cot", "cut"}},
+		{"___", "a", []string{"cot", "cut", "dog"}},
+		{"_o_", "o", []string{"cot", "dog"}},
+		{"c_t", "cto", []string{"cat", "cut"}},
+		{"____", "", nil},
+		{"x__", "x", nil},
+	}
+	for _, tt := range tests {
+		if got := Candidates(tt.pattern, []rune(tt.guessed), testDict); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Candidates(%q, %q) = %v, want %v", tt.pattern, tt.guessed, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		pattern string
+		guessed string
+		dict    []string
+		want    []Suggestion // the top of the ranking
+	}{
+		// ties on both scores keep letter frequency order
+		{"c_t", "ct", []string{"cat", "cot", "cut"}, []Suggestion{
+			{"a", 1.0 / 3, 5.0 / 3},
+			{"o", 1.0 / 3, 5.0 / 3},
+			{"u", 1.0 / 3, 5.0 / 3},
+		}},
+		// a letter in every word narrows nothing down, but still beats a certain miss
+		{"__", "", []string{"ab", "ac", "ad"}, []Suggestion{
+			{"d", 1.0 / 3, 5.0 / 3},
+			{"c", 1.0 / 3, 5.0 / 3},
+			{"b", 1.0 / 3, 5.0 / 3},
+			{"a", 1, 3},
+			{"e", 0, 3},
+		}},
+		{"___", "", testDict, []Suggestion{
+			{"o", 0.5, 2},
+			{"t", 0.75, 2.5},
+			{"c", 0.75, 2.5},
+		}},
+	}
+	for _, tt := range tests {
+		got := Suggest(tt.pattern, []rune(tt.guessed), tt.dict)
+		if len(got) < len(tt.want) {
+			t.Errorf("Suggest(%q, %q) = %v, want it to start %v", tt.pattern, tt.guessed, got, tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			if !sameSuggestion(got[i], want) {
+				t.Errorf("Suggest(%q, %q)[%d] = %+v, want %+v", tt.pattern, tt.guessed, i, got[i], want)
+			}
+		}
+	}
+}
+
+func TestSuggestSkipsGuessed(t *testing.T) {
+	for _, s := range Suggest("c_t", []rune("cto"), testDict) {
+		if s.Letter == "c" || s.Letter == "t" || s.Letter == "o" {
+			t.Errorf("Suggest suggested %q, which was already guessed", s.Letter)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		pattern string
+		guessed string
+		want    rune
+		ok      bool
+	}{
+		{"___", "", 'o', true},
+		{"_a_", "a", 't', true},
+		{"c_t", "cto", 'a', true},
+		{"zz_", "z", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Best(tt.pattern, []rune(tt.guessed), testDict)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Best(%q, %q) = %q, %v, want %q, %v", tt.pattern, tt.guessed, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	report := Analyze("cat", []rune("aect"), testDict)
+	want := []struct {
+		letter     string
+		hit        bool
+		rating     string
+		best       string
+		candidates int
+	}{
+		{"a", true, "good", "o", 4},
+		{"e", false, "poor", "t", 1},
+		{"c", true, "best", "t", 1},
+		{"t", true, "best", "t", 1},
+	}
+	if len(report.Guesses) != len(want) {
+		t.Fatalf("Analyze rated %d guesses, want %d", len(report.Guesses), len(want))
+	}
+	for i, w := range want {
+		g := report.Guesses[i]
+		if g.Letter != w.letter || g.Hit != w.hit || g.Rating != w.rating || g.Best != w.best || g.Candidates != w.candidates {
+			t.Errorf("guess %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if report.Accuracy != 0.5 {
+		t.Errorf("Accuracy = %v, want 0.5", report.Accuracy)
+	}
+}
+
+func TestAnalyzeUnknownWord(t *testing.T) {
+	report := Analyze("zebra", []rune("ez"), testDict)
+	for _, g := range report.Guesses {
+		if g.Rating != "unrated" {
+			t.Errorf("guess %q rated %q with no candidate words, want unrated", g.Letter, g.Rating)
+		}
+	}
+	if report.Accuracy != 0 {
+		t.Errorf("Accuracy = %v, want 0", report.Accuracy)
+	}
+}
+
+func sameSuggestion(a, b Suggestion) bool {
+	return a.Letter == b.Letter &&
+		math.Abs(a.HitProbability-b.HitProbability) < 1e-9 &&
+		math.Abs(a.ExpectedRemaining-b.ExpectedRemaining) < 1e-9
+}
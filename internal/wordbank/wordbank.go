@@ -0,0 +1,310 @@
+package wordbank
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+)
+
+//go:embed words.txt
+var defaultWords string
+
+// Words is the built in list the server picks from when no player sets the word
+var Words = parseWords(defaultWords)
+
+// Name of the bank built from Words, used when a lobby doesn't pick one
+const DefaultBank = "general"
+
+// Entry is one word in a bank, tagged with the category it belongs to
+type Entry struct {
+	Word       string `json:"word"`
+	Category   string `json:"category"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Clue       string `json:"clue,omitempty"`
+}
+
+// Limits on host supplied word lists
+const (
+	MaxCustomEntries = 1000
+	MaxClueLength    = 100
+)
+
+// Bank is a named list of words a host can pick for their lobby
+type Bank struct {
+	Name    string
+	Entries []Entry
+}
+
+// BankInfo describes a bank without giving its words away
+type BankInfo struct {
+	Name  string `json:"name"`
+	Words int    `json:"words"`
+}
+
+// Loaded banks by name
+var (
+	banks   = map[string]*Bank{DefaultBank: defaultBank()}
+	banksMu sync.RWMutex
+)
+
+func parseWords(raw string) []string {
+	var words []string
+	for _, line := range strings.Split(raw, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if !game.ValidateWord(word) {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+func defaultBank() *Bank {
+	bank := &Bank{Name: DefaultBank}
+	for _, word := range Words {
+		bank.Entries = append(bank.Entries, Entry{Word: word, Category: DefaultBank})
+	}
+	return bank
+}
+
+// NewGame starts a game on the entry's word, carrying its category and clue along
+func (e Entry) NewGame() game.Game {
+	g := game.NewGameInCategory(e.Word, e.Category)
+	g.Clue = e.Clue
+	return g
+}
+
+// ParseCustom reads a host supplied list with one
+// "word[,clue[,category[,difficulty]]]" entry per line. Plain text lists are just one word per line. Lines that can't be
+// used are returned in rejected with the reason.
+func ParseCustom(r io.Reader, defaultCategory string) (entries []Entry, rejected []string, err error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		word := strings.ToLower(strings.TrimSpace(record[0]))
+		if word == "" {
+			continue
+		}
+		if !game.ValidateWord(word) {
+			rejected = append(rejected, word+": words can only contain english letters")
+			continue
+		}
+		if len(entries) >= MaxCustomEntries {
+			rejected = append(rejected, word+": list is limited to "+strconv.Itoa(MaxCustomEntries)+" words")
+			continue
+		}
+
+		entry := Entry{Word: word, Category: defaultCategory}
+		if len(record) > 1 {
+			entry.Clue = strings.TrimSpace(record[1])
+		}
+		if len(entry.Clue) > MaxClueLength {
+			rejected = append(rejected, word+": clue is longer than "+strconv.Itoa(MaxClueLength)+" characters")
+			continue
+		}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			entry.Category = strings.TrimSpace(record[2])
+		}
+		if len(record) > 3 {
+			entry.Difficulty = strings.ToLower(strings.TrimSpace(record[3]))
+			if entry.Difficulty != "" && solver.BandIndex(entry.Difficulty) < 0 {
+				rejected = append(rejected, word+": difficulty must be easy, medium or hard")
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rejected, nil
+}
+
+// Random returns a random word from the built in list
+func Random() string {
+	return Words[rand.Intn(len(Words))]
+}
+
+// LoadBank reads a bank file with one "word,difficulty" entry per line. The file
+// name is the bank's name and the category of its words.
+func LoadBank(path string) (*Bank, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	bank := &Bank{Name: name}
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		word := strings.ToLower(strings.TrimSpace(record[0]))
+		if !game.ValidateWord(word) {
+			continue
+		}
+		entry := Entry{Word: word, Category: name}
+		if len(record) > 1 {
+			entry.Difficulty = strings.ToLower(strings.TrimSpace(record[1]))
+		}
+		if solver.BandIndex(entry.Difficulty) < 0 {
+			// unknown tags are left for TagUntagged to estimate
+			entry.Difficulty = ""
+		}
+		bank.Entries = append(bank.Entries, entry)
+	}
+
+	if len(bank.Entries) == 0 {
+		return nil, errors.New("word bank " + path + " has no words")
+	}
+	return bank, nil
+}
+
+// LoadDir loads every .txt bank in dir and registers it
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no word banks found in " + dir)
+	}
+
+	for _, path := range paths {
+		bank, err := LoadBank(path)
+		if err != nil {
+			return err
+		}
+		Register(bank)
+	}
+	return nil
+}
+
+// Register makes a bank available to lobbies by its name
+func Register(bank *Bank) {
+	banksMu.Lock()
+	defer banksMu.Unlock()
+	banks[bank.Name] = bank
+}
+
+// Get returns a bank by name
+func Get(name string) (*Bank, bool) {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	bank, ok := banks[name]
+	return bank, ok
+}
+
+// List describes every loaded bank, sorted by name
+func List() []BankInfo {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+
+	infos := make([]BankInfo, 0, len(banks))
+	for _, bank := range banks {
+		infos = append(infos, BankInfo{Name: bank.Name, Words: len(bank.Entries)})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// AllWords returns the words of every loaded bank, the built in list first
+func AllWords() []string {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+
+	names := make([]string, 0, len(banks))
+	for name := range banks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	words := append([]string(nil), Words...)
+	for _, name := range names {
+		for _, entry := range banks[name].Entries {
+			words = append(words, entry.Word)
+		}
+	}
+	return words
+}
+
+// TagUntagged fills in the difficulty of every entry that doesn't have one,
+// like the built in list, using band to rate the word. Banks aren't locked
+// entry by entry, so this is only for setting up before serving.
+func TagUntagged(band func(word string) string) {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	for _, bank := range banks {
+		for i := range bank.Entries {
+			if bank.Entries[i].Difficulty == "" {
+				bank.Entries[i].Difficulty = band(bank.Entries[i].Word)
+			}
+		}
+	}
+}
+
+// Random returns any entry from the bank
+func (b *Bank) Random() Entry {
+	return b.Entries[rand.Intn(len(b.Entries))]
+}
+
+// RandomByDifficulty returns a random entry tagged with the given difficulty,
+// or any entry when difficulty is empty
+func (b *Bank) RandomByDifficulty(difficulty string) (Entry, bool) {
+	return RandomEntry(b.Entries, difficulty)
+}
+
+// RandomEntry picks from any list of entries the way RandomByDifficulty
+// picks from a bank
+func RandomEntry(entries []Entry, difficulty string) (Entry, bool) {
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	if difficulty == "" {
+		return entries[rand.Intn(len(entries))], true
+	}
+
+	var matches []Entry
+	for _, entry := range entries {
+		if entry.Difficulty == difficulty {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) == 0 {
+		return Entry{}, false
+	}
+	return matches[rand.Intn(len(matches))], true
+}
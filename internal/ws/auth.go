@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+	"github.com/gorilla/websocket"
+)
+
+// AllowedOrigins are the sites whose pages may open a websocket. "*" allows
+// any site, and an empty list only allows pages served from this host.
+var AllowedOrigins []string
+
+// MaxConnectionsPerPlayer caps how many tabs or devices one player can have
+// open on a lobby at once
+var MaxConnectionsPerPlayer = 3
+
+// Checks the handshake's Origin against AllowedOrigins. Clients that aren't
+// browsers send no Origin and still need a session token.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if len(AllowedOrigins) == 0 {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range AllowedOrigins {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed == "*" || strings.EqualFold(allowed, u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Works out who is connecting from the session token they were given when
+// they joined, passed as ?token= or the session cookie. An id in the query
+// must match the token's.
+func authenticate(r *http.Request, lobbyID string) (string, error) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		if cookie, err := r.Cookie("session"); err == nil {
+			token = cookie.Value
+		}
+	}
+
+	playerID, ok := session.Authenticate(lobbyID, token)
+	if !ok {
+		return "", errors.New("not a player or spectator in this lobby")
+	}
+	if claimed := r.URL.Query().Get("id"); claimed != "" && claimed != playerID {
+		return "", errors.New("session belongs to someone else")
+	}
+	return playerID, nil
+}
+
+// Checks a lobby exists and the request carries a session for it, and that
+// the player isn't over their connection cap. Refusals are written to w.
+func admit(w http.ResponseWriter, r *http.Request, lobbyID string) (*session.Lobby, string, error) {
+	lobby, err := session.GetLobby(lobbyID)
+	if err != nil {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return nil, "", err
+	}
+	playerID, err := authenticate(r, lobbyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, "", err
+	}
+	if connectionsOf(lobby, playerID) >= MaxConnectionsPerPlayer {
+		http.Error(w, "Too many connections", http.StatusTooManyRequests)
+		return nil, "", errors.New("too many connections")
+	}
+	return lobby, playerID, nil
+}
+
+// Number of websockets and event streams a player has open on a lobby
+func connectionsOf(lobby *session.Lobby, playerID string) int {
+	lobby.ConnLock.Lock()
+	defer lobby.ConnLock.Unlock()
+	n := 0
+	for _, client := range subscribers(lobby) {
+		if client.playerID == playerID {
+			n++
+		}
+	}
+	return n
+}
+
+// Closes a connection for breaking the rules, telling the client why
+func refuse(conn *websocket.Conn, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason), time.Now().Add(writeWait))
+	conn.Close()
+}
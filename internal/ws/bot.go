@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/bot"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+)
+
+// How long the bot "thinks" before each guess
+const botGuessDelay = 1500 * time.Millisecond
+
+// The bot sets a new word for the human as soon as the lobby is waiting for words
+func botSubmit(lobby *session.Lobby) error {
+	if err := lobby.BotPickWord(); err != nil {
+		return commandError(CodeNoWords, "the bot has no word to set: "+err.Error())
+	}
+	BroadcastToLobby(lobby.ID, "p2Submit")
+	checkClassicStart(lobby)
+	return nil
+}
+
+// Plays the bot's game against the human's word, one guess at a time, until
+// the game finishes, a newer one starts or the lobby goes away
+func runBot(lobbyID string, botGame int) {
+	ticker := time.NewTicker(botGuessDelay)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		lobby, err := session.GetLobby(lobbyID)
+		if err != nil {
+			return
+		}
+		if !botGuess(lobby, botGame) {
+			return
+		}
+	}
+}
+
+// Makes one guess for the bot, holding the lobby's StateLock like any other
+// command. Returns false once the bot's game is over or has been replaced.
+func botGuess(lobby *session.Lobby, botGame int) bool {
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
+	if lobby.BotGame != botGame || lobby.State != session.StatePlaying || lobby.Game1.Status != game.InProgress {
+		return false
+	}
+
+	letter := bot.NextGuess(bot.Difficulty(lobby.BotDifficulty), lobby.Game1, lobby.SolverWords())
+	log.Printf("Bot guessed %c in lobby %s", letter, lobby.ID)
+	if err := handleGuess(nil, lobby.ID, lobby.Player2ID, GuessRequest{Letter: string(letter)}); err != nil {
+		log.Printf("Bot guess refused: %v", err)
+	}
+	BroadcastToLobby(lobby.ID, "instruction")
+	return true
+}
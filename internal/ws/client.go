@@ -0,0 +1,248 @@
+package ws
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/ratelimit"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write one message to the peer
+	writeWait = 10 * time.Second
+
+	// A peer that hasn't answered a ping in this long is treated as gone
+	pongWait = 60 * time.Second
+
+	// Pings go out a little more often than pongWait so a live peer never times out
+	pingPeriod = pongWait * 9 / 10
+
+	// Largest message a client may send. Commands are tiny, word lists go through REST.
+	maxMessageSize = 4096
+
+	// Messages queued for a connection before it counts as a slow consumer
+	sendBufferSize = 64
+)
+
+// SlowConsumerPolicy decides what happens to a connection that can't keep up
+// with the messages sent to it
+type SlowConsumerPolicy string
+
+const (
+	// DropMessages discards whatever doesn't fit in the connection's queue
+	DropMessages SlowConsumerPolicy = "drop"
+	// Disconnect closes the connection, the client can reconnect and resync
+	Disconnect SlowConsumerPolicy = "disconnect"
+)
+
+// SlowConsumers is the policy for every connection
+var SlowConsumers = Disconnect
+
+// Client is one viewer following a lobby, over a websocket or an event
+// stream. Messages are queued on send and written by the client's own writer
+// goroutine, since gorilla/websocket allows only one writer at a time.
+// Stream clients have no conn.
+type Client struct {
+	conn      *websocket.Conn
+	protocol  int
+	encoding  string
+	playerID  string
+	ip        string
+	send      chan frame
+	done      chan struct{}
+	stopped   chan struct{} // closed once the writer has exited
+	closeOnce sync.Once
+	goodbye   []byte // close frame the writer ends with
+
+	limiter    *ratelimit.Bucket // commands from this connection
+	violations int               // commands refused in a row
+
+	instruction InstructionEvent // last one sent, guarded by the lobby's ConnLock
+
+	spectator  bool
+	updateMu   sync.Mutex
+	pending    *frame    // board update held back from a spectator
+	lastUpdate time.Time // when the spectator was last sent one
+}
+
+func newClient(conn *websocket.Conn, protocol int, playerID string, ip string) *Client {
+	return &Client{
+		conn:     conn,
+		protocol: protocol,
+		encoding: EncodingJSON,
+		playerID: playerID,
+		ip:       ip,
+		send:     make(chan frame, sendBufferSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		limiter:  ratelimit.NewBucket(limits.PerConnection),
+	}
+}
+
+// An encoded message and its sequence number, 0 if it isn't a broadcast.
+// Binary frames are MessagePack. Frames shared between spectators come
+// prepared so they are compressed once.
+type frame struct {
+	seq      uint64
+	data     []byte
+	binary   bool
+	prepared *websocket.PreparedMessage
+}
+
+// Encodes and queues an event, numbered with seq if it is a broadcast.
+// Replies and snapshots aren't broadcasts and go out with seq 0, meaning none.
+func (c *Client) deliver(seq uint64, event any) {
+	// the bot plays without a connection
+	if c == nil {
+		return
+	}
+
+	f, err := encodeFrame(c.protocol, c.encoding, seq, event)
+	if err != nil {
+		log.Println("Failed to encode event:", err)
+		return
+	}
+	c.queue(f)
+}
+
+// Queues a broadcast frame for a spectator. Board updates are held back so a
+// spectator gets at most one every SpectatorUpdateInterval, the latest, which
+// is all a big audience needs of a fast game. Anything else flushes the held
+// update first so the order stays the same. Queueing never blocks, so it is
+// done under updateMu.
+func (c *Client) queueSpectator(t string, f frame) {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	if t != "update" || SpectatorUpdateInterval <= 0 {
+		if c.pending != nil {
+			c.queue(*c.pending)
+			c.pending = nil
+		}
+		c.queue(f)
+		return
+	}
+
+	if c.pending != nil {
+		// a flush is already scheduled and will send this one instead
+		c.pending = &f
+		return
+	}
+	wait := SpectatorUpdateInterval - time.Since(c.lastUpdate)
+	if wait <= 0 {
+		c.lastUpdate = time.Now()
+		c.queue(f)
+		return
+	}
+	c.pending = &f
+	time.AfterFunc(wait, c.flushUpdate)
+}
+
+// Sends a held back board update, if it hasn't gone already
+func (c *Client) flushUpdate() {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.lastUpdate = time.Now()
+	if c.pending != nil {
+		c.queue(*c.pending)
+		c.pending = nil
+	}
+}
+
+// Queues a message without blocking the caller, applying the slow consumer
+// policy when the queue is full
+func (c *Client) queue(f frame) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- f:
+	default:
+		if SlowConsumers == DropMessages {
+			log.Println("Dropped message for slow connection")
+			return
+		}
+		log.Println("Disconnecting slow connection")
+		c.close()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// Stops the writer. Closing the connection itself is left to the reader so
+// the lobby cleanup runs once.
+func (c *Client) close() {
+	c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+// Like close, with the code and reason the peer is told in the close frame
+func (c *Client) closeWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.goodbye = websocket.FormatCloseMessage(code, reason)
+		close(c.done)
+	})
+}
+
+// Writes queued messages and heartbeat pings until the client is closed or a
+// write fails
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		close(c.stopped)
+	}()
+
+	for {
+		select {
+		case f := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.write(f); err != nil {
+				log.Println("WebSocket write error:", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Println("WebSocket ping error:", err)
+				return
+			}
+		case <-c.done:
+			// whatever was queued first still goes out, e.g. the error saying why
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			for len(c.send) > 0 {
+				if err := c.write(<-c.send); err != nil {
+					return
+				}
+			}
+			c.conn.WriteMessage(websocket.CloseMessage, c.goodbye)
+			return
+		}
+	}
+}
+
+func (c *Client) write(f frame) error {
+	if f.prepared != nil {
+		return c.conn.WritePreparedMessage(f.prepared)
+	}
+	if f.binary {
+		return c.conn.WriteMessage(websocket.BinaryMessage, f.data)
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, f.data)
+}
+
+// Limits incoming messages and keeps the read deadline moving while the peer
+// answers pings, so half-open connections time out instead of holding the
+// lobby open forever
+func (c *Client) prepareReads() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
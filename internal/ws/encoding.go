@@ -0,0 +1,157 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encodings a websocket client can receive events in. JSON goes out in text
+// frames and is the default. MessagePack goes out in binary frames, keeps the
+// JSON field names and is only spoken with protocol version 2.
+const (
+	EncodingJSON        = "json"
+	EncodingMessagePack = "msgpack"
+)
+
+// MessagePack turns the hangman.v2.msgpack subprotocol on or off
+var MessagePack = true
+
+// SpectatorUpdateInterval is the least time between two board updates sent to
+// a spectator. Updates in between are dropped for the latest, 0 sends them all.
+var SpectatorUpdateInterval = 250 * time.Millisecond
+
+// EnableCompression negotiates permessage-deflate with clients that offer it.
+// Level is a compress/flate level, 0 keeps the default.
+func EnableCompression(level int) {
+	Upgrader.EnableCompression = true
+	compressionLevel = level
+}
+
+var compressionLevel int
+
+// Subprotocols the server offers, in order of preference
+func offeredSubprotocols() []string {
+	if MessagePack {
+		return []string{"hangman.v2.msgpack", "hangman.v2", "hangman.v1"}
+	}
+	return []string{"hangman.v2", "hangman.v1"}
+}
+
+// Picks the encoding for a new connection from the negotiated subprotocol, or
+// the ?encoding= query like ?protocol=. Version 1 is JSON only.
+func negotiateEncoding(r *http.Request, subprotocol string, protocol int) string {
+	if !MessagePack || protocol == ProtocolV1 {
+		return EncodingJSON
+	}
+	if subprotocol == "hangman.v2.msgpack" || (subprotocol == "" && r.URL.Query().Get("encoding") == EncodingMessagePack) {
+		return EncodingMessagePack
+	}
+	return EncodingJSON
+}
+
+// Encodes an event for a client, numbered with seq if it is a broadcast
+func encodeFrame(protocol int, encoding string, seq uint64, event any) (frame, error) {
+	if encoding == EncodingMessagePack {
+		data, err := encodeBinary(event, seq)
+		return frame{seq: seq, data: data, binary: true}, err
+	}
+	data, err := encodeEvent(protocol, event)
+	if err != nil {
+		return frame{}, err
+	}
+	if seq != 0 {
+		data = withSeq(data, seq, protocol)
+	}
+	return frame{seq: seq, data: data}, nil
+}
+
+// Encodes an event as a MessagePack map under its JSON field names, with seq
+// added first like withSeq does for JSON
+func encodeBinary(event any, seq uint64) ([]byte, error) {
+	var body bytes.Buffer
+	enc := msgpack.NewEncoder(&body)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(event); err != nil {
+		return nil, err
+	}
+	if seq == 0 {
+		return body.Bytes(), nil
+	}
+
+	dec := msgpack.NewDecoder(&body)
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return nil, errors.New("event isn't a map")
+	}
+	var out bytes.Buffer
+	enc = msgpack.NewEncoder(&out)
+	enc.UseCompactInts(true)
+	enc.EncodeMapLen(n + 1)
+	enc.EncodeString("seq")
+	enc.EncodeUint(seq)
+	out.Write(body.Bytes()) // the decoder only consumed the map header
+	return out.Bytes(), nil
+}
+
+// Decodes a message from a client. Binary frames are MessagePack and go
+// through JSON so payloads decode the same as they would in a text frame.
+func decodeMessage(messageType int, data []byte, msg *WSMessage) error {
+	if messageType == websocket.BinaryMessage {
+		var fields map[string]any
+		if err := msgpack.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, msg)
+}
+
+// A format is how one client needs its events encoded
+type format struct {
+	protocol int
+	encoding string
+}
+
+// One broadcast's event for every spectator. Spectators all see the same
+// thing, so it is encoded once per format and the frames are shared. Only
+// used under the lobby's ConnLock.
+type sharedEvent struct {
+	seq    uint64
+	event  any
+	frames map[format]frame
+}
+
+func newSharedEvent(seq uint64, event any) *sharedEvent {
+	return &sharedEvent{seq: seq, event: event, frames: make(map[format]frame)}
+}
+
+func (s *sharedEvent) frameFor(c *Client) (frame, error) {
+	key := format{c.protocol, c.encoding}
+	if f, ok := s.frames[key]; ok {
+		return f, nil
+	}
+	f, err := encodeFrame(c.protocol, c.encoding, s.seq, s.event)
+	if err != nil {
+		return frame{}, err
+	}
+	// a prepared message is compressed once however many connections get it
+	messageType := websocket.TextMessage
+	if f.binary {
+		messageType = websocket.BinaryMessage
+	}
+	if f.prepared, err = websocket.NewPreparedMessage(messageType, f.data); err != nil {
+		return frame{}, err
+	}
+	s.frames[key] = f
+	return f, nil
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"errors"
+	"log"
+	"unicode"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+)
+
+// Codes sent in error replies so clients can tell why a command was refused
+const (
+	CodeInvalidPayload   = "invalid_payload"
+	CodeUnknownType      = "unknown_type"
+	CodeInvalidWord      = "invalid_word"
+	CodeInvalidGuess     = "invalid_guess"
+	CodeAlreadyGuessed   = "already_guessed"
+	CodeNotYourTurn      = "not_your_turn"
+	CodeLobbyNotReady    = "lobby_not_ready"
+	CodeGameOver         = "game_over"
+	CodeAlreadyStarted   = "already_started"
+	CodeNotEnoughPlayers = "not_enough_players"
+	CodeWrongMode        = "wrong_mode"
+	CodeForbidden        = "forbidden"
+	CodeNotInGame        = "not_in_game"
+	CodeFiltered         = "filtered"
+	CodeRateLimited      = "rate_limited"
+	CodeNoWords          = "no_words"
+	CodeInternal         = "internal"
+)
+
+// CommandError is why a client command was refused
+type CommandError struct {
+	Code    string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func commandError(code string, message string) *CommandError {
+	return &CommandError{Code: code, Message: message}
+}
+
+// Answers a command with an ack, or an error saying why it was refused.
+// Version 1 clients only get replies when they tag their commands with an ID.
+func reply(client *Client, msg WSMessage, err error) {
+	if err != nil {
+		log.Printf("Refused %s: %v", msg.Type, err)
+	}
+	if client == nil || (client.protocol == ProtocolV1 && msg.ID == "") {
+		return
+	}
+
+	if err == nil {
+		send(client, AckEvent{Type: "ack", ID: msg.ID, Command: msg.Type})
+		return
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		cmdErr = commandError(CodeInternal, err.Error())
+	}
+	send(client, ErrorEvent{Type: "error", ID: msg.ID, Command: msg.Type, Code: cmdErr.Code, Message: cmdErr.Message})
+}
+
+// Plays a letter on a board, saying why when the guess doesn't count
+func playGuess(g *game.Game, letter rune) error {
+	if g.Status != game.InProgress {
+		return commandError(CodeGameOver, "this game is already over")
+	}
+	if !game.ValidateLetter(letter) {
+		return commandError(CodeInvalidGuess, "guesses must be english letters")
+	}
+	if g.Letters[unicode.ToLower(letter)] {
+		return commandError(CodeAlreadyGuessed, "that letter was already guessed")
+	}
+	g.Guess(letter)
+	return nil
+}
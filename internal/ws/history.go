@@ -0,0 +1,155 @@
+package ws
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+)
+
+// Broadcasts kept per lobby for clients that reconnect
+const historySize = 256
+
+// eventLog numbers a lobby's broadcasts and keeps the most recent ones, so a
+// client that drops off can catch up on what it missed instead of hanging
+// on a win or end it never heard about
+type eventLog struct {
+	seq          uint64
+	entries      [historySize]loggedEvent    // ring buffer indexed by seq
+	changed      chan struct{}               // closed and replaced on every broadcast
+	instructions map[string]InstructionEvent // viewer ID: instruction as of the latest broadcast
+}
+
+// One broadcast as each viewer saw it
+type loggedEvent struct {
+	seq    uint64
+	events map[string]any // viewer ID: event
+}
+
+// Numbers and stores a broadcast, returning its sequence number
+func (h *eventLog) add(events map[string]any) uint64 {
+	h.seq++
+	h.entries[h.seq%historySize] = loggedEvent{seq: h.seq, events: events}
+	close(h.changed)
+	h.changed = make(chan struct{})
+	return h.seq
+}
+
+// Records every viewer's current instruction, bumping the version if any has
+// changed since the last broadcast that logged no events. Instructions aren't
+// logged themselves, but long-pollers only notice a change by the version.
+func (h *eventLog) noteInstructions(instructions map[string]InstructionEvent, logged bool) {
+	changed := len(instructions) != len(h.instructions)
+	for id, event := range instructions {
+		if h.instructions[id] != event {
+			changed = true
+		}
+	}
+	h.instructions = instructions
+	if changed && !logged {
+		h.add(nil)
+	}
+}
+
+// Events one viewer got after seq, oldest first. ok is false when some of
+// them have already been dropped, or seq is from some other lobby's history.
+func (h *eventLog) since(seq uint64, id string) (missed []loggedEvent, ok bool) {
+	if seq > h.seq || h.seq-seq > historySize {
+		return nil, false
+	}
+	for s := seq + 1; s <= h.seq; s++ {
+		entry := h.entries[s%historySize]
+		if event, ok := entry.events[id]; ok {
+			missed = append(missed, loggedEvent{seq: s, events: map[string]any{id: event}})
+		}
+	}
+	return missed, true
+}
+
+// The lobby's event log, created on first use. The caller holds wsHub.Lock.
+func logFor(lobbyID string) *eventLog {
+	h, ok := wsHub.logs[lobbyID]
+	if !ok {
+		h = &eventLog{changed: make(chan struct{})}
+		wsHub.logs[lobbyID] = h
+	}
+	return h
+}
+
+// Replays what a reconnecting client missed after the sequence number it last
+// saw. Returns false when it has to start over from a snapshot instead. The
+// caller holds wsHub.Lock.
+func resume(client *Client, lobby *session.Lobby, since string) bool {
+	id := client.playerID
+	if since == "" {
+		return false
+	}
+	seq, err := strconv.ParseUint(since, 10, 64)
+	if err != nil {
+		return false
+	}
+	missed, ok := logFor(lobby.ID).since(seq, id)
+	if !ok {
+		return false
+	}
+	for _, entry := range missed {
+		client.deliver(entry.seq, entry.events[id])
+	}
+
+	// instructions aren't logged, the current one covers whatever was missed
+	lobby.ConnLock.Lock()
+	instruction := instructionView(lobby, id)
+	client.instruction = instruction
+	lobby.ConnLock.Unlock()
+	send(client, instruction)
+	return true
+}
+
+// Adds a sequence number to an encoded event. Version 1 clients get it as a
+// string like every other number.
+func withSeq(data []byte, seq uint64, version int) []byte {
+	value := strconv.FormatUint(seq, 10)
+	if version == ProtocolV1 {
+		value = strconv.Quote(value)
+	}
+	prefix := []byte(`{"seq":` + value)
+	if len(data) > 2 {
+		prefix = append(prefix, ',')
+	}
+	return append(prefix, data[1:]...)
+}
+
+// LobbyVersion is the seq of a lobby's latest broadcast. It moves on whenever
+// anything a player could see changes.
+func LobbyVersion(lobbyID string) uint64 {
+	wsHub.Lock.Lock()
+	defer wsHub.Lock.Unlock()
+	if h, ok := wsHub.logs[lobbyID]; ok {
+		return h.seq
+	}
+	return 0
+}
+
+// WaitForChange blocks until the lobby's version moves past version or ctx
+// ends, returning the version it is at then
+func WaitForChange(ctx context.Context, lobbyID string, version uint64) uint64 {
+	// only lobbies the hub broadcasts to get a log, which goes when they do.
+	// Any other lobby stays at version 0 and the wait just runs out.
+	var current uint64
+	var changed chan struct{}
+	wsHub.Lock.Lock()
+	if _, ok := wsHub.Lobbies[lobbyID]; ok {
+		h := logFor(lobbyID)
+		current, changed = h.seq, h.changed
+	}
+	wsHub.Lock.Unlock()
+	if current != version {
+		return current
+	}
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+	}
+	return LobbyVersion(lobbyID)
+}
@@ -0,0 +1,105 @@
+package ws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestLog() *eventLog {
+	return &eventLog{changed: make(chan struct{})}
+}
+
+func TestEventLogSince(t *testing.T) {
+	h := newTestLog()
+	h.add(map[string]any{"a": 1, "b": 1})
+	h.add(map[string]any{"a": 2})
+	h.add(map[string]any{"b": 3})
+
+	tests := []struct {
+		seq  uint64
+		id   string
+		want []uint64
+		ok   bool
+	}{
+		{0, "a", []uint64{1, 2}, true},
+		{0, "b", []uint64{1, 3}, true},
+		{1, "b", []uint64{3}, true},
+		{2, "a", nil, true},
+		{3, "a", nil, true},
+		{0, "c", nil, true},
+		// a seq the lobby hasn't got to is from some other lobby
+		{4, "a", nil, false},
+	}
+	for _, tt := range tests {
+		missed, ok := h.since(tt.seq, tt.id)
+		var got []uint64
+		for _, entry := range missed {
+			got = append(got, entry.seq)
+			if _, ok := entry.events[tt.id]; !ok || len(entry.events) != 1 {
+				t.Errorf("since(%d, %q) entry %d = %v, want only %q's event", tt.seq, tt.id, entry.seq, entry.events, tt.id)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+			t.Errorf("since(%d, %q) = %v, %v, want %v, %v", tt.seq, tt.id, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEventLogWraps(t *testing.T) {
+	h := newTestLog()
+	total := uint64(historySize + 10)
+	for i := uint64(1); i <= total; i++ {
+		h.add(map[string]any{"a": i})
+	}
+
+	tests := []struct {
+		seq    uint64
+		missed int
+		ok     bool
+	}{
+		{0, 0, false},
+		// the oldest broadcast still kept has been overwritten
+		{total - historySize - 1, 0, false},
+		{total - historySize, historySize, true},
+		{total - 1, 1, true},
+		{total, 0, true},
+	}
+	for _, tt := range tests {
+		missed, ok := h.since(tt.seq, "a")
+		if len(missed) != tt.missed || ok != tt.ok {
+			t.Errorf("since(%d) = %d events, %v, want %d, %v", tt.seq, len(missed), ok, tt.missed, tt.ok)
+			continue
+		}
+		for i, entry := range missed {
+			if want := tt.seq + uint64(i) + 1; entry.seq != want || entry.events["a"] != want {
+				t.Errorf("since(%d)[%d] = seq %d, event %v, want %d", tt.seq, i, entry.seq, entry.events["a"], want)
+			}
+		}
+	}
+}
+
+func TestNoteInstructions(t *testing.T) {
+	h := newTestLog()
+	waiting := map[string]InstructionEvent{"a": {Type: "instruction", Instruction: "Waiting"}}
+	guessing := map[string]InstructionEvent{"a": {Type: "instruction", Instruction: "Guess"}}
+
+	tests := []struct {
+		instructions map[string]InstructionEvent
+		logged       bool
+		seq          uint64
+	}{
+		{waiting, false, 1},
+		// nothing changed, so long-pollers have nothing to notice
+		{waiting, false, 1},
+		{guessing, false, 2},
+		// the broadcast that changed it already moved the version on
+		{waiting, true, 2},
+		{map[string]InstructionEvent{}, false, 3},
+	}
+	for i, tt := range tests {
+		h.noteInstructions(tt.instructions, tt.logged)
+		if h.seq != tt.seq {
+			t.Errorf("step %d: seq = %d, want %d", i, h.seq, tt.seq)
+		}
+	}
+}
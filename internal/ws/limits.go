@@ -0,0 +1,127 @@
+package ws
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/ratelimit"
+)
+
+// RateLimits caps how fast commands are accepted. Each is checked on every
+// command, so a player can't get around the per connection limit by opening
+// more tabs, or the per player limit by joining more lobbies from one address.
+type RateLimits struct {
+	PerConnection ratelimit.Limit
+	PerPlayer     ratelimit.Limit
+	PerIP         ratelimit.Limit
+	MaxViolations int // refused commands in a row before the connection is closed
+}
+
+var (
+	limits = RateLimits{
+		PerConnection: ratelimit.Limit{Rate: 5, Burst: 10},
+		PerPlayer:     ratelimit.Limit{Rate: 8, Burst: 15},
+		PerIP:         ratelimit.Limit{Rate: 20, Burst: 40},
+		MaxViolations: 20,
+	}
+	playerLimiter = ratelimit.NewLimiter(limits.PerPlayer)
+	ipLimiter     = ratelimit.NewLimiter(limits.PerIP)
+)
+
+// How long a session posting commands is refused for once it turns abusive.
+// There's no connection to close, so it is shut out for a while instead.
+const floodCooldown = time.Minute
+
+// Commands posted over HTTP get a new Client each time, so the bucket and
+// refusal count a connection would keep are kept per player session instead
+type sessionLimit struct {
+	limiter      *ratelimit.Bucket
+	violations   int
+	blockedUntil time.Time
+	last         time.Time
+}
+
+var (
+	sessionLimits   = make(map[string]*sessionLimit) // lobby ID/player ID: limits
+	sessionLimitsMu sync.Mutex
+	sessionCalls    int
+)
+
+// ConfigureRateLimits replaces the command rate limits. Zero values keep the
+// current ones. Call it before serving, existing connections keep their limits.
+func ConfigureRateLimits(c RateLimits) {
+	if c.PerConnection.Rate > 0 {
+		limits.PerConnection = c.PerConnection
+	}
+	if c.PerPlayer.Rate > 0 {
+		limits.PerPlayer = c.PerPlayer
+		playerLimiter = ratelimit.NewLimiter(c.PerPlayer)
+	}
+	if c.PerIP.Rate > 0 {
+		limits.PerIP = c.PerIP
+		ipLimiter = ratelimit.NewLimiter(c.PerIP)
+	}
+	if c.MaxViolations > 0 {
+		limits.MaxViolations = c.MaxViolations
+	}
+}
+
+// Checks a command against every limit that applies to the client sending it.
+// Only the client's reader goroutine calls this.
+func (c *Client) allow(lobbyID string) error {
+	// every bucket is charged so none can be drained through the others
+	ok := c.limiter.Allow()
+	ok = playerLimiter.Allow(lobbyID+"/"+c.playerID) && ok
+	ok = ipLimiter.Allow(c.ip) && ok
+	if !ok {
+		c.violations++
+		return commandError(CodeRateLimited, "slow down, too many commands")
+	}
+	c.violations = 0
+	return nil
+}
+
+// Checks a posted command against the limits of the session sending it, the
+// way allow does for a connection
+func allowSession(client *Client, lobbyID string) error {
+	sessionLimitsMu.Lock()
+	defer sessionLimitsMu.Unlock()
+
+	now := time.Now()
+	sessionCalls++
+	if sessionCalls%1024 == 0 {
+		// idle sessions would start out with a full bucket again anyway
+		for key, s := range sessionLimits {
+			if now.Sub(s.last) > floodCooldown && now.After(s.blockedUntil) {
+				delete(sessionLimits, key)
+			}
+		}
+	}
+
+	key := lobbyID + "/" + client.playerID
+	s, ok := sessionLimits[key]
+	if !ok {
+		s = &sessionLimit{limiter: ratelimit.NewBucket(limits.PerConnection)}
+		sessionLimits[key] = s
+	}
+	s.last = now
+	if now.Before(s.blockedUntil) {
+		return commandError(CodeRateLimited, "slow down, too many commands")
+	}
+
+	client.limiter, client.violations = s.limiter, s.violations
+	err := client.allow(lobbyID)
+	s.violations = client.violations
+	if client.abusive() {
+		log.Printf("Refusing commands from %s in lobby %s for flooding", client.ip, lobbyID)
+		s.blockedUntil = now.Add(floodCooldown)
+		s.violations = 0
+	}
+	return err
+}
+
+// Whether the client has kept on sending after being told to slow down
+func (c *Client) abusive() bool {
+	return c.violations >= limits.MaxViolations
+}
@@ -0,0 +1,406 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+)
+
+// Protocol versions a client can speak. Version 1 is the original protocol,
+// where payloads are bare strings and every field of an event is a string.
+// Version 2 uses the request structs below as payloads and sends numbers and
+// booleans as JSON numbers and booleans.
+const (
+	ProtocolV1     = 1
+	ProtocolV2     = 2
+	LatestProtocol = ProtocolV2
+)
+
+// WebSocket subprotocol names, offered in Sec-WebSocket-Protocol
+var subprotocols = map[string]int{
+	"hangman.v2.msgpack": ProtocolV2,
+	"hangman.v2":         ProtocolV2,
+	"hangman.v1":         ProtocolV1,
+}
+
+// Picks the protocol for a new connection from the negotiated subprotocol, or
+// the ?protocol= query for clients that can't set one. Clients that ask for
+// neither get version 1 so older frontends keep working.
+func negotiateProtocol(r *http.Request, subprotocol string) int {
+	if v, ok := subprotocols[subprotocol]; ok {
+		return v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("protocol")); err == nil && v >= ProtocolV1 && v <= LatestProtocol {
+		return v
+	}
+	return ProtocolV1
+}
+
+// Requests: the payload of each message type a client can send
+
+type GuessRequest struct {
+	Letter string `json:"letter"`
+}
+
+// Word is the setter's own word. Random asks the server to pick one from the
+// lobby's word bank instead, optionally of one difficulty.
+type SubmitRequest struct {
+	Word       string `json:"word,omitempty"`
+	Random     bool   `json:"random,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+}
+
+type ChatRequest struct {
+	Text string `json:"text"`
+}
+
+type EstimateRequest struct {
+	Word string `json:"word"`
+}
+
+// EmptyRequest is the payload of messages that carry nothing, such as ready or hint
+type EmptyRequest struct{}
+
+// Version 1 clients send the letter, word or chat text as a bare string
+func (r *GuessRequest) UnmarshalJSON(data []byte) error {
+	type plain GuessRequest
+	return unmarshalLegacy(data, &r.Letter, (*plain)(r))
+}
+
+func (r *SubmitRequest) UnmarshalJSON(data []byte) error {
+	type plain SubmitRequest
+	return unmarshalLegacy(data, &r.Word, (*plain)(r))
+}
+
+func (r *ChatRequest) UnmarshalJSON(data []byte) error {
+	type plain ChatRequest
+	return unmarshalLegacy(data, &r.Text, (*plain)(r))
+}
+
+func (r *EstimateRequest) UnmarshalJSON(data []byte) error {
+	type plain EstimateRequest
+	return unmarshalLegacy(data, &r.Word, (*plain)(r))
+}
+
+// Version 1 restart and update messages carry the player's ID or nothing at
+// all, neither of which the server needs
+func (r *EmptyRequest) UnmarshalJSON(data []byte) error {
+	return nil
+}
+
+func unmarshalLegacy(data []byte, field *string, v any) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, field)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Events: everything the server sends. Player boards are named after the
+// player doing the guessing, so player1's board is Game2.
+
+type HelloEvent struct {
+	Type     string `json:"type"`
+	Protocol int    `json:"protocol"`
+	Encoding string `json:"encoding"` // json, or msgpack in binary frames
+	Versions []int  `json:"versions"`
+}
+
+// BoardEvent is a classic or race player's view of both boards
+type BoardEvent struct {
+	Type                   string `json:"type"`
+	Revealed               string `json:"revealed"`
+	Attempts               int    `json:"attempts"`
+	OpponentRevealed       string `json:"opponent_revealed"`
+	OpponentAttempts       int    `json:"opponent_attempts"`
+	GuessedLetters         string `json:"guessed_letters"`
+	OpponentGuessedLetters string `json:"opponent_guessed_letters"`
+	Category               string `json:"category"`
+	Clue                   string `json:"clue"`
+}
+
+// SpectatorBoardEvent is the read-only view of both boards. Hidden words are never included.
+type SpectatorBoardEvent struct {
+	Type                  string `json:"type"`
+	Spectator             bool   `json:"spectator"`
+	Player1               string `json:"player1"`
+	Player2               string `json:"player2"`
+	Player1Revealed       string `json:"player1_revealed"`
+	Player1Attempts       int    `json:"player1_attempts"`
+	Player1GuessedLetters string `json:"player1_guessed_letters"`
+	Player1Category       string `json:"player1_category"`
+	Player2Revealed       string `json:"player2_revealed"`
+	Player2Attempts       int    `json:"player2_attempts"`
+	Player2GuessedLetters string `json:"player2_guessed_letters"`
+	Player2Category       string `json:"player2_category"`
+}
+
+// RoyaleBoardEvent shows survivors their own game and everyone else the standings
+type RoyaleBoardEvent struct {
+	Type           string             `json:"type"`
+	Mode           string             `json:"mode"`
+	Round          int                `json:"round"`
+	Remaining      int                `json:"remaining"`
+	Spectator      bool               `json:"spectator,omitempty"`
+	Standings      []session.Standing `json:"standings,omitempty"`
+	Revealed       string             `json:"revealed,omitempty"`
+	Attempts       int                `json:"attempts,omitempty"`
+	GuessedLetters string             `json:"guessed_letters,omitempty"`
+	Category       string             `json:"category,omitempty"`
+	Clue           string             `json:"clue,omitempty"`
+}
+
+// CoopBoardEvent is the shared co-op board and who guessed which letters
+type CoopBoardEvent struct {
+	Type                  string `json:"type"`
+	Mode                  string `json:"mode"`
+	Revealed              string `json:"revealed"`
+	Attempts              int    `json:"attempts"`
+	GuessedLetters        string `json:"guessed_letters"`
+	Player1GuessedLetters string `json:"player1_guessed_letters"`
+	Player2GuessedLetters string `json:"player2_guessed_letters"`
+	LastGuessPlayer       string `json:"last_guess_player"`
+	LastGuessName         string `json:"last_guess_name"`
+	Category              string `json:"category"`
+	Clue                  string `json:"clue"`
+}
+
+// TurnBoardEvent is the turn based board. Only the setter is sent the word.
+type TurnBoardEvent struct {
+	Type           string `json:"type"`
+	Mode           string `json:"mode"`
+	Revealed       string `json:"revealed"`
+	Attempts       int    `json:"attempts"`
+	GuessedLetters string `json:"guessed_letters"`
+	Category       string `json:"category"`
+	Clue           string `json:"clue"`
+	Setter         string `json:"setter"`
+	Turn           string `json:"turn"`
+	YourTurn       bool   `json:"your_turn"`
+	Word           string `json:"word,omitempty"`
+}
+
+// TeamBoardEvent is a team member's board and the other team's progress
+type TeamBoardEvent struct {
+	Type                   string `json:"type"`
+	Mode                   string `json:"mode"`
+	Team                   int    `json:"team"`
+	YourTurn               bool   `json:"your_turn"`
+	Revealed               string `json:"revealed"`
+	Attempts               int    `json:"attempts"`
+	GuessedLetters         string `json:"guessed_letters"`
+	Category               string `json:"category"`
+	Clue                   string `json:"clue"`
+	OpponentRevealed       string `json:"opponent_revealed"`
+	OpponentAttempts       int    `json:"opponent_attempts"`
+	OpponentGuessedLetters string `json:"opponent_guessed_letters"`
+}
+
+type JoinEvent struct {
+	Type        string `json:"type"`
+	Message     string `json:"message"` // name of the player who joined
+	PlayerCount string `json:"playerCount"`
+}
+
+type SpectatorsEvent struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// PlayerEvent announces something a seat did: ready, submit, restart or close.
+// Player is "1" or "2", or "spectator" for a co-op word from a spectator.
+type PlayerEvent struct {
+	Type    string `json:"type"`
+	Player  string `json:"player,omitempty"`
+	Team    int    `json:"team,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ResultEvent is a win or a loss. Player is a seat, "team" in co-op, or a
+// member ID in turn based mode. Team is set instead in team mode.
+type ResultEvent struct {
+	Type   string `json:"type"`
+	Player string `json:"player,omitempty"`
+	Team   int    `json:"team,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Word   string `json:"word"`
+}
+
+type RaceOverEvent struct {
+	Type                 string `json:"type"`
+	Winner               int    `json:"winner"` // 0 for a draw
+	Name                 string `json:"name,omitempty"`
+	Word                 string `json:"word"`
+	Player1RevealedCount int    `json:"player1_revealed_count"`
+	Player2RevealedCount int    `json:"player2_revealed_count"`
+}
+
+type EliminatedEvent struct {
+	Type      string `json:"type"`
+	Player    string `json:"player"`
+	Name      string `json:"name,omitempty"`
+	Round     int    `json:"round"`
+	Remaining int    `json:"remaining"`
+}
+
+type NextWordEvent struct {
+	Type      string `json:"type"`
+	Round     int    `json:"round"`
+	Remaining int    `json:"remaining"`
+}
+
+type StandingsEvent struct {
+	Type      string             `json:"type"`
+	Round     int                `json:"round"`
+	Standings []session.Standing `json:"standings"`
+}
+
+type RoyaleWinnerEvent struct {
+	Type   string `json:"type"`
+	Player string `json:"player"`
+	Name   string `json:"name"`
+}
+
+// TurnEvent says whose turn it is to guess in turn based mode
+type TurnEvent struct {
+	Type   string `json:"type"`
+	Player string `json:"player"`
+	Name   string `json:"name"`
+}
+
+// TeamTurnEvent says which member of each team guesses next
+type TeamTurnEvent struct {
+	Type        string `json:"type"`
+	Team1Player string `json:"team1_player,omitempty"`
+	Team1Name   string `json:"team1_name,omitempty"`
+	Team2Player string `json:"team2_player,omitempty"`
+	Team2Name   string `json:"team2_name,omitempty"`
+}
+
+type SetterEvent struct {
+	Type   string `json:"type"`
+	Player string `json:"player"`
+	Name   string `json:"name,omitempty"`
+	Round  int    `json:"round"`
+}
+
+type ReportEvent struct {
+	Type   string        `json:"type"`
+	Report solver.Report `json:"report"`
+}
+
+type RoundEvent struct {
+	Type         string `json:"type"`
+	Round        int    `json:"round"`
+	BestOf       int    `json:"best_of"`
+	Player1Score int    `json:"player1_score"`
+	Player2Score int    `json:"player2_score"`
+}
+
+type NextRoundEvent struct {
+	Type   string `json:"type"`
+	Round  int    `json:"round"`
+	BestOf int    `json:"best_of"`
+}
+
+type SeriesEndEvent struct {
+	Type         string `json:"type"`
+	Winner       int    `json:"winner"` // 0 for a tie
+	Name         string `json:"name"`
+	Player1Score int    `json:"player1_score"`
+	Player2Score int    `json:"player2_score"`
+}
+
+type EndEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type ChatEvent struct {
+	Type    string `json:"type"`
+	Team    int    `json:"team"`
+	Player  string `json:"player"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+type HintEvent struct {
+	Type        string              `json:"type"`
+	Suggestions []solver.Suggestion `json:"suggestions"`
+}
+
+type RandomWordEvent struct {
+	Type       string `json:"type"`
+	Word       string `json:"word"`
+	Category   string `json:"category"`
+	Clue       string `json:"clue"`
+	Difficulty string `json:"difficulty"`
+}
+
+type EstimateEvent struct {
+	Type     string          `json:"type"`
+	Word     string          `json:"word"`
+	Estimate solver.Estimate `json:"estimate"`
+	Allowed  bool            `json:"allowed"`
+	Reason   string          `json:"reason,omitempty"`
+}
+
+// InstructionEvent tells a player what they and their opponent are doing now.
+// It is worked out by the server and sent whenever it changes.
+type InstructionEvent struct {
+	Type                string        `json:"type"`
+	Phase               session.Phase `json:"phase"`
+	Instruction         string        `json:"instruction"`
+	OpponentPhase       session.Phase `json:"opponent_phase,omitempty"`
+	OpponentInstruction string        `json:"opponent_instruction,omitempty"`
+}
+
+// AckEvent confirms a command was carried out. ID is the one the client sent with it.
+type AckEvent struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Command string `json:"command"`
+}
+
+// ErrorEvent says why a command was refused, Code being one of the Code constants
+type ErrorEvent struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Command string `json:"command"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SubmitErrorEvent is how version 1 clients hear a word was turned down
+type SubmitErrorEvent struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// Renders an event for the given protocol version. Version 1 clients get every
+// top level number and boolean as a string, as the original protocol sent them.
+func encodeEvent(version int, event any) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || version != ProtocolV1 {
+		return data, err
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case json.Number:
+			fields[key] = v.String()
+		case bool:
+			fields[key] = strconv.FormatBool(v)
+		}
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Payload struct for each message type a client can send
+var requestTypes = []struct {
+	Type    string
+	Payload any
+}{
+	{"update", EmptyRequest{}},
+	{"guess", GuessRequest{}},
+	{"submit", SubmitRequest{}},
+	{"restart", EmptyRequest{}},
+	{"ready", EmptyRequest{}},
+	{"start", EmptyRequest{}},
+	{"chat", ChatRequest{}},
+	{"hint", EmptyRequest{}},
+	{"estimate", EstimateRequest{}},
+	{"sync", EmptyRequest{}},
+}
+
+// Event struct behind each type of message the server sends
+var eventTypes = []struct {
+	Types []string
+	Event any
+}{
+	{[]string{"hello"}, HelloEvent{}},
+	{[]string{"sync"}, SyncEvent{}},
+	{[]string{"update", "start_game"}, BoardEvent{}},
+	{[]string{"update", "start_game"}, SpectatorBoardEvent{}},
+	{[]string{"update", "start_game"}, RoyaleBoardEvent{}},
+	{[]string{"update", "start_game"}, CoopBoardEvent{}},
+	{[]string{"update", "start_game"}, TurnBoardEvent{}},
+	{[]string{"update", "start_game"}, TeamBoardEvent{}},
+	{[]string{"join"}, JoinEvent{}},
+	{[]string{"spectators"}, SpectatorsEvent{}},
+	{[]string{"ready", "submit", "restart", "close"}, PlayerEvent{}},
+	{[]string{"win", "lost"}, ResultEvent{}},
+	{[]string{"race_over"}, RaceOverEvent{}},
+	{[]string{"eliminated"}, EliminatedEvent{}},
+	{[]string{"next_word"}, NextWordEvent{}},
+	{[]string{"standings"}, StandingsEvent{}},
+	{[]string{"royale_winner"}, RoyaleWinnerEvent{}},
+	{[]string{"turn"}, TurnEvent{}},
+	{[]string{"turn"}, TeamTurnEvent{}},
+	{[]string{"setter"}, SetterEvent{}},
+	{[]string{"report"}, ReportEvent{}},
+	{[]string{"round"}, RoundEvent{}},
+	{[]string{"next_round"}, NextRoundEvent{}},
+	{[]string{"series_end"}, SeriesEndEvent{}},
+	{[]string{"end"}, EndEvent{}},
+	{[]string{"chat"}, ChatEvent{}},
+	{[]string{"hint"}, HintEvent{}},
+	{[]string{"random_word"}, RandomWordEvent{}},
+	{[]string{"estimate"}, EstimateEvent{}},
+	{[]string{"submit_error"}, SubmitErrorEvent{}},
+	{[]string{"instruction"}, InstructionEvent{}},
+	{[]string{"ack"}, AckEvent{}},
+	{[]string{"error"}, ErrorEvent{}},
+}
+
+// Schema describes the latest protocol as a JSON Schema document. ClientMessage
+// covers what clients send and ServerMessage what the server sends back.
+func Schema() map[string]any {
+	defs := make(map[string]any)
+
+	var clientMessages []any
+	for _, r := range requestTypes {
+		clientMessages = append(clientMessages, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"type":    map[string]any{"const": r.Type},
+				"id":      map[string]any{"type": "string"},
+				"payload": schemaFor(reflect.TypeOf(r.Payload), defs),
+			},
+			"required": []string{"type"},
+		})
+	}
+
+	var serverMessages []any
+	for _, e := range eventTypes {
+		ref := schemaFor(reflect.TypeOf(e.Event), defs)
+		def := defs[reflect.TypeOf(e.Event).Name()].(map[string]any)
+		properties := def["properties"].(map[string]any)
+		properties["type"] = map[string]any{"type": "string", "enum": e.Types}
+		// broadcasts are numbered by the lobby's eventLog, see BroadcastToLobby
+		if _, ok := properties["seq"]; !ok {
+			properties["seq"] = map[string]any{"type": "integer"}
+		}
+		serverMessages = append(serverMessages, ref)
+	}
+
+	defs["ClientMessage"] = map[string]any{"oneOf": clientMessages}
+	defs["ServerMessage"] = map[string]any{"oneOf": serverMessages}
+
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "Hangman WebSocket protocol",
+		"version": LatestProtocol,
+		"$defs":   defs,
+		"oneOf": []any{
+			map[string]any{"$ref": "#/$defs/ClientMessage"},
+			map[string]any{"$ref": "#/$defs/ServerMessage"},
+		},
+	}
+}
+
+// HandleSchema serves the protocol's JSON Schema so clients can be generated from it
+func HandleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(Schema())
+}
+
+// Builds the schema for a Go type, adding named structs to defs and
+// referring to them by name
+func schemaFor(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), defs)}
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), defs)
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, done := defs[t.Name()]; done {
+			return ref
+		}
+		def := map[string]any{"type": "object"}
+		defs[t.Name()] = def
+
+		properties := make(map[string]any)
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaFor(field.Type, defs)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		def["properties"] = properties
+		def["required"] = required
+		return ref
+	}
+	return map[string]any{}
+}
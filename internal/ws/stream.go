@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Kalani-Kawaguchi/Hangman/internal/ratelimit"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+	"github.com/gorilla/mux"
+)
+
+// How often an idle event stream gets a comment line, so proxies that cut
+// quiet connections leave it alone
+const streamKeepAlive = 15 * time.Second
+
+// HandleEvents follows a lobby over server-sent events, for clients behind
+// proxies that break websockets. Each event's data is exactly the message a
+// websocket client would get, and broadcasts carry their seq as the event ID
+// so EventSource picks up where it left off after a drop. Commands go to
+// HandleCommand.
+func HandleEvents(w http.ResponseWriter, r *http.Request) {
+	lobbyID := mux.Vars(r)["id"]
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	lobby, playerID, err := admit(w, r, lobbyID)
+	if err != nil {
+		log.Println("Event stream refused:", err)
+		return
+	}
+
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	client := newClient(nil, negotiateProtocol(r, ""), playerID, ratelimit.IP(r))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lobby.StateLock.Lock()
+	wsHub.Lock.Lock()
+	if _, exists := wsHub.Lobbies[lobbyID]; !exists {
+		wsHub.Lobbies[lobbyID] = lobby
+	}
+	wsHub.clientsMu.Lock()
+	if wsHub.streams[lobbyID] == nil {
+		wsHub.streams[lobbyID] = make(map[*Client]bool)
+	}
+	wsHub.streams[lobbyID][client] = true
+	wsHub.clientsMu.Unlock()
+	lobby.ConnLock.Lock()
+	client.spectator = lobby.IsSpectator(playerID)
+	lobby.ConnLock.Unlock()
+	greet(client, lobby, since)
+	wsHub.Lock.Unlock()
+	lobby.StateLock.Unlock()
+	log.Printf("Added event stream to lobby speaking protocol v%d", client.protocol)
+
+	client.streamTo(w, flusher, r)
+	closeStream(lobby, client)
+}
+
+// Writes queued messages as events until the request ends or the client is
+// closed as a slow consumer
+func (c *Client) streamTo(w http.ResponseWriter, flusher http.Flusher, r *http.Request) {
+	ticker := time.NewTicker(streamKeepAlive)
+	defer func() {
+		ticker.Stop()
+		close(c.stopped)
+	}()
+
+	for {
+		var err error
+		select {
+		case f := <-c.send:
+			if f.seq != 0 {
+				_, err = fmt.Fprintf(w, "id: %d\n", f.seq)
+			}
+			if err == nil {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", f.data)
+			}
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-c.done:
+			return
+		}
+		if err != nil {
+			log.Println("Event stream write error:", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func closeStream(lobby *session.Lobby, client *Client) {
+	wsHub.Lock.Lock()
+	defer wsHub.Lock.Unlock()
+
+	client.close()
+	wsHub.clientsMu.Lock()
+	delete(wsHub.streams[lobby.ID], client)
+	if len(wsHub.streams[lobby.ID]) == 0 {
+		delete(wsHub.streams, lobby.ID)
+	}
+	wsHub.clientsMu.Unlock()
+
+	if _, ok := wsHub.Lobbies[lobby.ID]; ok {
+		leave(lobby, client.playerID)
+	}
+}
+
+// HandleCommand runs one command for a client that follows the lobby over
+// HandleEvents. The body is the same message a websocket client would send.
+// Whatever the command sends back to its sender alone, the ack or error and
+// things like hints and snapshots, comes back as a JSON array in the response,
+// while broadcasts arrive on the stream as usual.
+func HandleCommand(w http.ResponseWriter, r *http.Request) {
+	lobbyID := mux.Vars(r)["id"]
+	if _, err := session.GetLobby(lobbyID); err != nil {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+	playerID, err := authenticate(r, lobbyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var msg WSMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageSize)).Decode(&msg); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// lobbies are only registered by a subscriber attaching, and leave with the
+	// last one. Commands for a lobby nobody follows would go unheard anyway.
+	wsHub.Lock.Lock()
+	_, followed := wsHub.Lobbies[lobbyID]
+	wsHub.Lock.Unlock()
+	if !followed {
+		http.Error(w, "Open the lobby's event stream first", http.StatusConflict)
+		return
+	}
+
+	client := newClient(nil, negotiateProtocol(r, ""), playerID, ratelimit.IP(r))
+	if err = allowSession(client, lobbyID); err != nil {
+		reply(client, msg, err)
+	} else {
+		err = handleMessage(client, lobbyID, msg)
+	}
+
+	// everything was queued synchronously, so the queue holds the whole reply
+	client.close()
+	replies := []json.RawMessage{}
+	for len(client.send) > 0 {
+		replies = append(replies, (<-client.send).data)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(commandStatus(err))
+	json.NewEncoder(w).Encode(replies)
+}
+
+// HTTP status for a command's outcome
+func commandStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return http.StatusInternalServerError
+	}
+	switch cmdErr.Code {
+	case CodeInvalidPayload, CodeUnknownType, CodeInvalidWord, CodeInvalidGuess, CodeFiltered:
+		return http.StatusBadRequest
+	case CodeForbidden, CodeNotInGame:
+		return http.StatusForbidden
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusConflict
+}
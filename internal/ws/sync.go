@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+)
+
+// SyncEvent is everything one viewer needs to rebuild their screen from scratch.
+// It is sent on every connect, so a refreshed page picks up where it left off.
+type SyncEvent struct {
+	Type             string             `json:"type"`
+	Seq              uint64             `json:"seq"` // last broadcast included, pass it back as ?since= when reconnecting
+	LobbyID          string             `json:"lobby_id"`
+	LobbyName        string             `json:"lobby_name"`
+	Mode             string             `json:"mode"`
+	Phase            string             `json:"phase"` // waiting, playing or ended
+	PlayerID         string             `json:"player_id"`
+	Seat             string             `json:"seat"` // "1", "2", "member" or "spectator"
+	Team             int                `json:"team,omitempty"`
+	Player1          string             `json:"player1"`
+	Player2          string             `json:"player2"`
+	Player1Ready     bool               `json:"player1_ready"` // word submitted, or ready in server picked modes
+	Player2Ready     bool               `json:"player2_ready"`
+	Player1Restarted bool               `json:"player1_restarted"`
+	Player2Restarted bool               `json:"player2_restarted"`
+	YourWord         string             `json:"your_word,omitempty"` // the word this viewer set for their opponent
+	Board            any                `json:"board,omitempty"`
+	Turn             string             `json:"turn,omitempty"`   // ID of whoever guesses next
+	Setter           string             `json:"setter,omitempty"` // ID of the turn based setter
+	Round            int                `json:"round"`
+	BestOf           int                `json:"best_of"`
+	Player1Score     int                `json:"player1_score"`
+	Player2Score     int                `json:"player2_score"`
+	Spectators       int                `json:"spectators"`
+	Standings        []session.Standing `json:"standings,omitempty"`
+	Instruction      InstructionEvent   `json:"instruction"`
+}
+
+// Builds the snapshot for one viewer. Hidden words are left out the same way
+// the board views leave them out. The caller holds the lobby's ConnLock.
+func syncView(lobby *session.Lobby, id string) SyncEvent {
+	sync := SyncEvent{
+		Type:             "sync",
+		LobbyID:          lobby.ID,
+		LobbyName:        lobby.Name,
+		Mode:             string(lobby.Mode),
+		Phase:            string(lobby.State),
+		PlayerID:         id,
+		Player1:          lobby.Player1,
+		Player2:          lobby.Player2,
+		Player1Ready:     lobby.Game1Ready,
+		Player2Ready:     lobby.Game2Ready,
+		Player1Restarted: lobby.Player1Restarted,
+		Player2Restarted: lobby.Player2Restarted,
+		Round:            lobby.Round,
+		BestOf:           lobby.BestOf,
+		Player1Score:     lobby.Player1Score,
+		Player2Score:     lobby.Player2Score,
+		Spectators:       len(lobby.Spectators),
+	}
+
+	switch {
+	case lobby.IsSpectator(id):
+		sync.Seat = "spectator"
+	case lobby.IsMultiplayer():
+		sync.Seat = "member"
+		if m := lobby.Member(id); m != nil {
+			sync.Team = m.Team
+		} else {
+			sync.Seat = "spectator"
+		}
+	case id == lobby.Player1ID:
+		sync.Seat = "1"
+	case id == lobby.Player2ID:
+		sync.Seat = "2"
+	default:
+		sync.Seat = "spectator"
+	}
+
+	// boards only exist once words are set
+	if lobby.State != session.StateWaiting {
+		sync.Board = viewFor("board", lobby, id)
+	}
+
+	// classic setters get their own word back so a refresh doesn't offer to set it again
+	if lobby.Mode == session.ModeClassic {
+		if sync.Seat == "1" && lobby.Game1Ready {
+			sync.YourWord = lobby.Game1.Word
+		} else if sync.Seat == "2" && lobby.Game2Ready {
+			sync.YourWord = lobby.Game2.Word
+		}
+	}
+
+	switch lobby.Mode {
+	case session.ModeTurns:
+		sync.Setter = lobby.SetterID
+		if m := lobby.CurrentGuesser(); m != nil {
+			sync.Turn = m.ID
+		}
+		sync.Standings = lobby.Standings()
+	case session.ModeTeams:
+		if m := lobby.TeamTurn(sync.Team); sync.Team != 0 && m != nil {
+			sync.Turn = m.ID
+		}
+	case session.ModeRoyale:
+		sync.Standings = lobby.Standings()
+	}
+	sync.Instruction = instructionView(lobby, id)
+	return sync
+}
+
+// Sends a fresh snapshot to one connection. The caller holds the lobby's
+// StateLock so no command is halfway through, and wsHub.Lock so no broadcast
+// slips in between the snapshot and its seq.
+func sendSync(client *Client, lobby *session.Lobby) {
+	lobby.ConnLock.Lock()
+	sync := syncView(lobby, client.playerID)
+	sync.Seq = logFor(lobby.ID).seq
+	client.instruction = sync.Instruction
+	lobby.ConnLock.Unlock()
+	send(client, sync)
+}
+
+// Clients can ask for a new snapshot whenever they think they've drifted.
+// Commands already hold the lobby's StateLock.
+func handleSync(client *Client, lobbyID string, playerID string) error {
+	wsHub.Lock.Lock()
+	defer wsHub.Lock.Unlock()
+	lobby, ok := wsHub.Lobbies[lobbyID]
+	if !ok {
+		return commandError(CodeLobbyNotReady, "lobby not found")
+	}
+	sendSync(client, lobby)
+	return nil
+}
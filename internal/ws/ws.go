@@ -3,38 +3,57 @@ package ws
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"unicode"
 
+	"github.com/Kalani-Kawaguchi/Hangman/internal/filter"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/game"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/ratelimit"
 	"github.com/Kalani-Kawaguchi/Hangman/internal/session"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/solver"
+	"github.com/Kalani-Kawaguchi/Hangman/internal/wordbank"
 	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
-	Lobbies map[string]*session.Lobby
-	Lock    sync.Mutex
+	Lobbies   map[string]*session.Lobby
+	Lock      sync.Mutex
+	clients   map[*websocket.Conn]*Client // send queue and protocol of each connection
+	streams   map[string]map[*Client]bool // event stream clients of each lobby, protected by clientsMu
+	clientsMu sync.Mutex
+	logs      map[string]*eventLog // recent broadcasts of each lobby, protected by Lock
 }
 
+// WSMessage is a client message. Payload is decoded into the request struct
+// for its type once the type is known. ID is the client's own tag for the
+// command, echoed back in the ack or error reply.
 type WSMessage struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
-}
-
-type InstructionPayload struct {
-	Player      string `json:"player"`
-	Instruction string `json:"instruction"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload"`
 }
 
 var wsHub = &Hub{
 	Lobbies: make(map[string]*session.Lobby),
+	clients: make(map[*websocket.Conn]*Client),
+	streams: make(map[string]map[*Client]bool),
+	logs:    make(map[string]*eventLog),
 }
 
+// Longest team chat message that gets passed on
+const maxChatLength = 500
+
+// Number of letters suggested in a practice hint
+const maxHints = 3
+
+// Upgrader for every websocket. The subprotocols offered are filled in per
+// connection, see offeredSubprotocols.
 var Upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: checkOrigin,
 }
 
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -45,164 +64,351 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer cleanupConnection(lobbyID, conn)
 
+	client := clientOf(conn)
+	client.prepareReads()
+
 	for {
 		var msg WSMessage
-		if err := conn.ReadJSON(&msg); err != nil {
+		messageType, data, err := conn.ReadMessage()
+		if err == nil {
+			err = decodeMessage(messageType, data, &msg)
+		}
+		if err != nil {
 			log.Println("WebSocket read error:", err)
 			break
 		}
-		handleMessage(conn, lobbyID, msg)
-
+		if err := client.allow(lobbyID); err != nil {
+			reply(client, msg, err)
+			if client.abusive() {
+				log.Printf("Disconnecting %s from lobby %s for flooding", client.ip, lobbyID)
+				client.closeWith(websocket.ClosePolicyViolation, "rate limit exceeded")
+				<-client.stopped
+				break
+			}
+			continue
+		}
+		handleMessage(client, lobbyID, msg)
 	}
 }
 
 func setupWebSocket(w http.ResponseWriter, r *http.Request) (*websocket.Conn, string, error) {
-	// Get player and lobby id's from url
-	playerID := r.URL.Query().Get("id")
-	if playerID == "" {
-		return nil, "", fmt.Errorf("player not identified")
-	}
+	// Everything that can be checked is checked before upgrading, so a
+	// refused client gets a plain HTTP error it can read
 	lobbyID := r.URL.Query().Get("lobby")
 	if lobbyID == "" {
 		http.Error(w, "Missing lobby ID", http.StatusBadRequest)
 		return nil, "", errors.New("missing lobby ID")
 	}
+	lobby, playerID, err := admit(w, r, lobbyID)
+	if err != nil {
+		return nil, "", err
+	}
 
-	conn, err := Upgrader.Upgrade(w, r, nil)
+	upgrader := Upgrader
+	upgrader.Subprotocols = offeredSubprotocols()
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("no connection")
 		return nil, "", err
 	}
 
+	// the snapshot sent on greeting has to be of a lobby no command is halfway through
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
 	wsHub.Lock.Lock()
 	defer wsHub.Lock.Unlock()
 
-	lobby, err := session.GetLobby(lobbyID)
-	if err != nil {
+	// the lobby may have closed, or other tabs connected, while upgrading
+	if _, err := session.GetLobby(lobbyID); err != nil {
+		refuse(conn, "lobby closed")
 		return nil, "", err
 	}
+	if connectionsOf(lobby, playerID) >= MaxConnectionsPerPlayer {
+		refuse(conn, "too many connections")
+		return nil, "", errors.New("too many connections")
+	}
 	if _, exists := wsHub.Lobbies[lobbyID]; !exists {
 		wsHub.Lobbies[lobbyID] = lobby
 	}
 
+	protocol := negotiateProtocol(r, conn.Subprotocol())
+	client := newClient(conn, protocol, playerID, ratelimit.IP(r))
+	client.encoding = negotiateEncoding(r, conn.Subprotocol(), protocol)
+	if compressionLevel != 0 {
+		conn.SetCompressionLevel(compressionLevel)
+	}
+	wsHub.clientsMu.Lock()
+	wsHub.clients[conn] = client
+	wsHub.clientsMu.Unlock()
+	go client.writePump()
+
 	lobby.ConnLock.Lock()
 	lobby.Clients[conn] = playerID
+	client.spectator = lobby.IsSpectator(playerID)
 	lobby.ConnLock.Unlock()
 
-	log.Println("Added connection to lobby")
+	greet(client, lobby, r.URL.Query().Get("since"))
+	log.Printf("Added connection to lobby speaking protocol v%d in %s", protocol, client.encoding)
 	return conn, lobbyID, nil
 }
 
-func handleMessage(conn *websocket.Conn, lobbyID string, msg WSMessage) {
-	log.Printf("Received from %s: %v\n", lobbyID, msg)
+// Sends a new client the hello, then catches it up: a reconnecting client
+// passes the last seq it saw and only gets what it missed, anyone else gets a
+// snapshot. The caller holds the lobby's StateLock and wsHub.Lock.
+func greet(client *Client, lobby *session.Lobby, since string) {
+	send(client, HelloEvent{Type: "hello", Protocol: client.protocol, Encoding: client.encoding, Versions: []int{ProtocolV1, ProtocolV2}})
+	if !resume(client, lobby, since) {
+		sendSync(client, lobby)
+	}
+}
+
+// Queues an event for one client in the protocol version it speaks
+func send(client *Client, event any) {
+	client.deliver(0, event)
+}
+
+// Client behind a websocket, nil once it has gone
+func clientOf(conn *websocket.Conn) *Client {
+	wsHub.clientsMu.Lock()
+	defer wsHub.clientsMu.Unlock()
+	return wsHub.clients[conn]
+}
+
+// Decodes a message's payload into the request struct for its type
+func decode[T any](msg WSMessage) (T, error) {
+	var req T
+	if len(msg.Payload) == 0 {
+		return req, nil
+	}
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return req, commandError(CodeInvalidPayload, "bad "+msg.Type+" payload: "+err.Error())
+	}
+	return req, nil
+}
+
+// Runs a command and replies to it, returning the handler's error
+func handleMessage(client *Client, lobbyID string, msg WSMessage) error {
+	log.Printf("Received from %s: %s %s\n", lobbyID, msg.Type, msg.Payload)
 
 	lobby, err := session.GetLobby(lobbyID)
 	if err != nil {
-		return
+		return err
+	}
+	lobby.ConnLock.Lock()
+	playerID := client.playerID
+	spectator := lobby.IsSpectator(playerID)
+	lobby.ConnLock.Unlock()
+
+	// one command at a time per lobby, the bot's guesses included
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
+	err = dispatch(client, lobby, playerID, spectator, msg)
+	reply(client, msg, err)
+	// some handlers change state after their last broadcast
+	BroadcastToLobby(lobbyID, "instruction")
+	return err
+}
+
+// Runs a command through its handler, returning why it was refused if it was
+func dispatch(client *Client, lobby *session.Lobby, playerID string, spectator bool, msg WSMessage) error {
+	lobbyID := lobby.ID
+
+	if msg.Type == "sync" {
+		return handleSync(client, lobbyID, playerID)
+	}
+
+	// Spectators only get a read-only view of the lobby, but may act as the
+	// third party that sets the word for a co-op round
+	if spectator {
+		if msg.Type == "submit" && lobby.Mode == session.ModeCoop {
+			req, err := decode[SubmitRequest](msg)
+			if err != nil {
+				return err
+			}
+			return handleCoopWord(client, lobbyID, playerID, req)
+		}
+		return commandError(CodeForbidden, "spectators can only watch")
 	}
-	playerID := lobby.Clients[conn]
 
 	switch msg.Type {
 	case "update":
-		handleUpdate(conn, lobbyID, playerID, msg.Payload)
+		return handleUpdate(client, lobbyID, playerID)
 	case "instruction":
-		handleInstruction(conn, lobbyID, playerID, msg.Payload)
+		return commandError(CodeForbidden, "instructions are worked out by the server")
 	case "guess":
-		handleGuess(conn, lobbyID, playerID, msg.Payload)
+		req, err := decode[GuessRequest](msg)
+		if err != nil {
+			return err
+		}
+		return handleGuess(client, lobbyID, playerID, req)
 	case "submit":
-		handleSubmit(conn, lobbyID, playerID, msg.Payload)
+		req, err := decode[SubmitRequest](msg)
+		if err != nil {
+			return err
+		}
+		return handleSubmit(client, lobbyID, playerID, req)
 	case "restart":
-		handleRestart(lobbyID, msg.Payload)
-	default:
-		log.Println("Unknown message type:", msg.Type)
+		return handleRestart(lobbyID, playerID)
+	case "ready":
+		return handleReady(client, lobbyID, playerID)
+	case "start":
+		return handleStart(client, lobbyID, playerID)
+	case "chat":
+		req, err := decode[ChatRequest](msg)
+		if err != nil {
+			return err
+		}
+		return handleChat(client, lobbyID, playerID, req)
+	case "hint":
+		return handleHint(client, lobbyID, playerID)
+	case "estimate":
+		req, err := decode[EstimateRequest](msg)
+		if err != nil {
+			return err
+		}
+		return handleEstimate(client, lobbyID, playerID, req)
 	}
+	return commandError(CodeUnknownType, "unknown message type "+msg.Type)
 }
 
-func handleInstruction(conn *websocket.Conn, lobbyID string, playerID string, payload interface{}) {
-	// Marshal the interface{} payload back to JSON
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		log.Println("Failed to marshal payload:", err)
-		return
-	}
-
-	// Unmarshal into InstructionPayload struct
-	var instructionData InstructionPayload
-	if err := json.Unmarshal(payloadBytes, &instructionData); err != nil {
-		log.Println("Failed to unmarshal instruction payload:", err)
-		return
-	}
-	log.Println("Unmarshalled payload successfully")
-
+func handleRestart(lobbyID string, playerID string) error {
 	lobby := wsHub.Lobbies[lobbyID]
-	if instructionData.Player == "One" {
-		lobby.Player1Instruction = instructionData.Instruction
-	} else if instructionData.Player == "Two" {
-		lobby.Player2Instruction = instructionData.Instruction
-	} else if instructionData.Player == "OneOpp" {
-		lobby.Player1OppInstruction = instructionData.Instruction
-	} else if instructionData.Player == "TwoOpp" {
-		lobby.Player2OppInstruction = instructionData.Instruction
+	if lobby.IsMultiplayer() {
+		// only the host can bring everyone back for another game
+		if playerID != lobby.Player1ID {
+			return commandError(CodeForbidden, "only the host can restart the game")
+		}
+		if lobby.State != session.StateEnded {
+			return commandError(CodeAlreadyStarted, "the game isn't over yet")
+		}
+		lobby.ResetMembers()
+		lobby.State = session.StateWaiting
+		BroadcastToLobby(lobbyID, "p1Restart")
+		return nil
 	}
 
-}
-
-func handleRestart(lobbyID string, payload interface{}) {
-	playerID, ok := payload.(string)
-	if !ok {
-		log.Print("playerID not found")
-		return
-	}
-	lobby := wsHub.Lobbies[lobbyID]
 	if playerID == lobby.Player1ID {
 		lobby.Player1Restarted = true
 		BroadcastToLobby(lobbyID, "p1Restart")
+		if lobby.HasBot() {
+			lobby.Player2Restarted = true
+			BroadcastToLobby(lobbyID, "p2Restart")
+		}
 	} else if playerID == lobby.Player2ID {
 		lobby.Player2Restarted = true
 		BroadcastToLobby(lobbyID, "p2Restart")
+	} else {
+		return commandError(CodeNotInGame, "you aren't seated in this lobby")
 	}
 	log.Printf("%s", lobby.Player2ID)
 	if (lobby.Player2 != "" && (lobby.Player1Restarted && lobby.Player2Restarted)) || (lobby.Player2 == "" && lobby.Player1Restarted) {
+		// a rematch after a finished series starts a fresh one
+		if lobby.IsSeries() && lobby.SeriesOver() {
+			lobby.ResetSeries()
+		}
 		lobby.State = session.StateWaiting
+		if lobby.HasBot() {
+			return botSubmit(lobby)
+		}
 	}
+	return nil
 }
 
-func handleUpdate(conn *websocket.Conn, lobbyID string, playerID string, payload interface{}) {
-	return
+func handleUpdate(client *Client, lobbyID string, playerID string) error {
+	return nil
 }
 
-func handleGuess(conn *websocket.Conn, lobbyID string, playerID string, payload interface{}) {
+func handleGuess(client *Client, lobbyID string, playerID string, req GuessRequest) error {
 	lobby := wsHub.Lobbies[lobbyID]
-	letter, ok := payload.(string)
-	if !ok {
-		log.Print("Letter could not be asserted to string")
-		return
+	letter := req.Letter
+	if len(letter) != 1 {
+		return commandError(CodeInvalidGuess, "guess exactly one letter")
 	}
 
-	if lobby.State == session.StateWaiting {
-		log.Print("Lobby not ready")
-		return
+	if lobby.State == session.StateWaiting || lobby.State == session.StateEnded {
+		return commandError(CodeLobbyNotReady, "the game hasn't started")
+	}
+
+	if lobby.Mode == session.ModeCoop {
+		return handleCoopGuess(lobby, playerID, rune(letter[0]))
+	}
+
+	if lobby.Mode == session.ModeRoyale {
+		return handleRoyaleGuess(lobby, playerID, rune(letter[0]))
+	}
+
+	if lobby.Mode == session.ModeTeams {
+		return handleTeamGuess(lobby, playerID, rune(letter[0]))
+	}
+
+	if lobby.Mode == session.ModeTurns {
+		return handleTurnGuess(lobby, playerID, rune(letter[0]))
 	}
 
 	if playerID == lobby.Player1ID {
-		lobby.Game2.Guess(rune(letter[0]))
+		if err := playGuess(&lobby.Game2, rune(letter[0])); err != nil {
+			return err
+		}
 		sendWinLost(lobby.Game2, lobbyID, "p1")
 	} else if playerID == lobby.Player2ID {
-		lobby.Game1.Guess(rune(letter[0]))
+		if err := playGuess(&lobby.Game1, rune(letter[0])); err != nil {
+			return err
+		}
 		sendWinLost(lobby.Game1, lobbyID, "p2")
+	} else {
+		return commandError(CodeNotInGame, "you aren't seated in this lobby")
 	}
 
 	// broadcast updated word revealed progress
 	BroadcastToLobby(lobbyID, "update")
 
+	if lobby.Mode == session.ModeRace {
+		if _, over := lobby.RaceWinner(); over {
+			lobby.State = session.StateEnded
+			BroadcastToLobby(lobbyID, "race_over")
+			BroadcastToLobby(lobbyID, "report")
+			BroadcastToLobby(lobbyID, "end")
+		}
+		return nil
+	}
+
 	// check if player2 is in lobby and both games are finished OR if Only player1 is in the lobby and their game is finished
 	if (lobby.Player2 != "" && (lobby.Game1.Status != game.InProgress && lobby.Game2.Status != game.InProgress)) ||
 		(lobby.Game2.Status != game.InProgress && lobby.Player2 == "") {
 		lobby.State = session.StateEnded
+		BroadcastToLobby(lobbyID, "report")
+		if lobby.IsSeries() && lobby.Player2 != "" {
+			endSeriesRound(lobby)
+			return nil
+		}
 		BroadcastToLobby(lobbyID, "end")
+	}
+	return nil
+}
+
+// Scores a finished round of a best-of-N series, then either announces the series
+// winner or moves both players straight on to picking words for the next round
+func endSeriesRound(lobby *session.Lobby) {
+	lobby.ScoreRound()
+	BroadcastToLobby(lobby.ID, "round")
+
+	if lobby.SeriesOver() {
+		BroadcastToLobby(lobby.ID, "series_end")
+		BroadcastToLobby(lobby.ID, "end")
 		return
 	}
 
+	BroadcastToLobby(lobby.ID, "end")
+	lobby.Round++
+	lobby.Player1Restarted = false
+	lobby.Player2Restarted = false
+	lobby.State = session.StateWaiting
+	BroadcastToLobby(lobby.ID, "next_round")
+	if lobby.HasBot() {
+		if err := botSubmit(lobby); err != nil {
+			log.Printf("Lobby %s: %v", lobby.ID, err)
+		}
+	}
 }
 
 // Checks if the latest guess results in the game state being updated to win or lost
@@ -225,40 +431,589 @@ func sendWinLost(g game.Game, lobbyID string, player string) {
 	}
 }
 
-func handleSubmit(conn *websocket.Conn, lobbyID string, playerID string, payload interface{}) {
+// Players in co-op and other server picked modes don't submit words, they mark
+// themselves ready and the round starts once everyone in the lobby is ready
+func handleReady(client *Client, lobbyID string, playerID string) error {
 	lobby := wsHub.Lobbies[lobbyID]
-	word, ok := payload.(string)
-	if !ok {
-		log.Print("Letter could not be asserted to string")
-		return
+	if lobby.Mode == session.ModeClassic {
+		return commandError(CodeWrongMode, "players pick the words in classic mode")
+	}
+
+	if lobby.IsMultiplayer() {
+		return commandError(CodeWrongMode, "the host starts the game in this mode")
+	}
+
+	if lobby.State != session.StateWaiting {
+		return commandError(CodeAlreadyStarted, "the round already started")
 	}
 
 	if playerID == lobby.Player1ID {
-		log.Println("checking player1ID")
-		if game.ValidateWord(word) {
-			log.Println("validating word for player1")
-			lobby.Game1 = game.NewGame(word)
-			lobby.Game1Ready = true
-			BroadcastToLobby(lobbyID, "p1Submit")
-			log.Println("New Game1 Created")
+		lobby.Game1Ready = true
+		BroadcastToLobby(lobbyID, "p1Ready")
+	} else if playerID == lobby.Player2ID {
+		lobby.Game2Ready = true
+		BroadcastToLobby(lobbyID, "p2Ready")
+	} else {
+		return commandError(CodeNotInGame, "you aren't seated in this lobby")
+	}
+
+	if lobby.Game1Ready && (lobby.Game2Ready || lobby.Player2 == "") {
+		switch lobby.Mode {
+		case session.ModeCoop:
+			return startCoopGame(lobby)
+		case session.ModeRace:
+			return startRaceGame(lobby)
+		}
+	}
+	return nil
+}
+
+// The host starts a battle royale once enough players have joined
+func handleStart(client *Client, lobbyID string, playerID string) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	if lobby.Mode != session.ModeRoyale {
+		return commandError(CodeWrongMode, "only battle royale lobbies are started by the host")
+	}
+
+	if playerID != lobby.Player1ID {
+		return commandError(CodeForbidden, "only the host can start the game")
+	}
+
+	if lobby.State != session.StateWaiting {
+		return commandError(CodeAlreadyStarted, "the game already started")
+	}
+
+	if len(lobby.Members) < 2 {
+		return commandError(CodeNotEnoughPlayers, "at least two players are needed to start")
+	}
+
+	entry, err := lobby.PickEntry("")
+	if err != nil {
+		return commandError(CodeNoWords, err.Error())
+	}
+	lobby.StartRoyaleRound(entry)
+	BroadcastToLobby(lobbyID, "start_game")
+	BroadcastToLobby(lobbyID, "standings")
+	log.Println("Battle royale started")
+	return nil
+}
+
+// Every surviving player guesses their own copy of the round's word.
+// Losing the word eliminates the player, who stays on as a spectator.
+func handleRoyaleGuess(lobby *session.Lobby, playerID string, letter rune) error {
+	member := lobby.Member(playerID)
+	if member == nil || member.Eliminated {
+		return commandError(CodeForbidden, "eliminated players can only watch")
+	}
+
+	if err := playGuess(&member.Game, letter); err != nil {
+		return err
+	}
+	BroadcastToLobby(lobby.ID, "update")
+
+	if member.Game.Status == game.Won {
+		member.WordsSolved++
+	} else if member.Game.Status == game.Lost {
+		lobby.Eliminate(member)
+		lobby.LastEliminated = member.ID
+		BroadcastToLobby(lobby.ID, "eliminated")
+	}
+
+	return checkRoyaleRound(lobby)
+}
+
+// Ends the battle royale once a single player is left standing, otherwise moves
+// the survivors on to the next word when all of them are done with this one.
+// With no word to move on to, the lobby waits for the host to start it again.
+func checkRoyaleRound(lobby *session.Lobby) error {
+	if len(lobby.Alive()) <= 1 {
+		lobby.State = session.StateEnded
+		BroadcastToLobby(lobby.ID, "royale_winner")
+		BroadcastToLobby(lobby.ID, "standings")
+		BroadcastToLobby(lobby.ID, "report")
+		BroadcastToLobby(lobby.ID, "end")
+		return nil
+	}
+
+	if lobby.RoundFinished() {
+		entry, err := lobby.PickEntry("")
+		if err != nil {
+			lobby.State = session.StateWaiting
+			BroadcastToLobby(lobby.ID, "standings")
+			return commandError(CodeNoWords, err.Error())
+		}
+		lobby.Round++
+		lobby.StartRoyaleRound(entry)
+		BroadcastToLobby(lobby.ID, "next_word")
+		BroadcastToLobby(lobby.ID, "start_game")
+		BroadcastToLobby(lobby.ID, "standings")
+	}
+	return nil
+}
+
+// MemberLeft takes a player out of a lobby with more than two players and settles
+// the game if their leaving decided it. It reports whether the player was seated.
+func MemberLeft(lobbyID string, playerID string) bool {
+	lobby, err := session.GetLobby(lobbyID)
+	if err != nil {
+		return false
+	}
+	lobby.StateLock.Lock()
+	defer lobby.StateLock.Unlock()
+
+	if !lobby.RemoveMember(playerID) {
+		return false
+	}
+
+	if lobby.Mode == session.ModeTeams {
+		if lobby.State == session.StatePlaying {
+			checkTeamGame(lobby)
+		}
+		return true
+	}
+
+	if lobby.Mode == session.ModeTurns {
+		checkTurnGame(lobby)
+		return true
+	}
+
+	BroadcastToLobby(lobbyID, "standings")
+	if lobby.State == session.StatePlaying {
+		lobby.LastEliminated = playerID
+		BroadcastToLobby(lobbyID, "eliminated")
+		if err := checkRoyaleRound(lobby); err != nil {
+			log.Printf("Lobby %s: %v", lobbyID, err)
+		}
+	}
+	return true
+}
+
+// Team 1 sets Game1 for team 2 and team 2 sets Game2 for team 1, the same way
+// Player1 and Player2 do in a classic lobby. Any teammate can submit the word.
+func handleTeamSubmit(lobby *session.Lobby, playerID string, entry wordbank.Entry) error {
+	member := lobby.Member(playerID)
+	if member == nil {
+		return commandError(CodeNotInGame, "you aren't on a team")
+	}
+
+	if lobby.State != session.StateWaiting {
+		return commandError(CodeAlreadyStarted, "the words are already set")
+	}
+
+	if member.Team == 1 {
+		lobby.Game1 = entry.NewGame()
+		lobby.Game1Ready = true
+		BroadcastToLobby(lobby.ID, "t1Submit")
+	} else {
+		lobby.Game2 = entry.NewGame()
+		lobby.Game2Ready = true
+		BroadcastToLobby(lobby.ID, "t2Submit")
+	}
+
+	if lobby.Game1Ready && lobby.Game2Ready && lobby.TeamTurn(1) != nil && lobby.TeamTurn(2) != nil {
+		lobby.State = session.StatePlaying
+		lobby.Team1Turn = 0
+		lobby.Team2Turn = 0
+		BroadcastToLobby(lobby.ID, "update")
+		BroadcastToLobby(lobby.ID, "start_game")
+		BroadcastToLobby(lobby.ID, "turn")
+	}
+	return nil
+}
+
+// Teammates share one board and alternate guesses, so only the member whose
+// turn it is may guess
+func handleTeamGuess(lobby *session.Lobby, playerID string, letter rune) error {
+	member := lobby.Member(playerID)
+	if member == nil {
+		return commandError(CodeNotInGame, "you aren't on a team")
+	}
+
+	if lobby.TeamTurn(member.Team) != member {
+		return commandError(CodeNotYourTurn, "it's a teammate's turn to guess")
+	}
+
+	board := &lobby.Game2
+	if member.Team == 2 {
+		board = &lobby.Game1
+	}
+	if err := playGuess(board, letter); err != nil {
+		return err
+	}
+	lobby.AdvanceTeamTurn(member.Team)
+
+	sendTeamWinLost(*board, lobby.ID, member.Team)
+	BroadcastToLobby(lobby.ID, "update")
+	BroadcastToLobby(lobby.ID, "turn")
+	checkTeamGame(lobby)
+	return nil
+}
+
+// Ends a team game once both boards are finished, or when a whole team has left
+func checkTeamGame(lobby *session.Lobby) {
+	if (lobby.Game1.Status != game.InProgress && lobby.Game2.Status != game.InProgress) ||
+		lobby.TeamTurn(1) == nil || lobby.TeamTurn(2) == nil {
+		lobby.State = session.StateEnded
+		BroadcastToLobby(lobby.ID, "report")
+		BroadcastToLobby(lobby.ID, "end")
+	}
+}
+
+// Same as sendWinLost, but announces the team that solved or failed its board
+func sendTeamWinLost(g game.Game, lobbyID string, team int) {
+	if g.WinOrLost() {
+		if g.Status == game.Won {
+			BroadcastToLobby(lobbyID, "t"+strconv.Itoa(team)+"Win")
+		} else if g.Status == game.Lost {
+			BroadcastToLobby(lobbyID, "t"+strconv.Itoa(team)+"Lose")
+		}
+	}
+}
+
+// Only the setter picks the word in turn based mode, and the round needs
+// at least one other player to guess it
+func handleTurnSubmit(lobby *session.Lobby, playerID string, entry wordbank.Entry) error {
+	if playerID != lobby.SetterID {
+		return commandError(CodeNotYourTurn, "only the setter can pick the word")
+	}
+
+	if lobby.State != session.StateWaiting {
+		return commandError(CodeAlreadyStarted, "the word is already set")
+	}
+
+	if len(lobby.Guessers()) == 0 {
+		return commandError(CodeNotEnoughPlayers, "nobody is here to guess the word")
+	}
+
+	lobby.Game1 = entry.NewGame()
+	lobby.Game1Ready = true
+	lobby.TurnIndex = 0
+	lobby.State = session.StatePlaying
+	BroadcastToLobby(lobby.ID, "update")
+	BroadcastToLobby(lobby.ID, "start_game")
+	BroadcastToLobby(lobby.ID, "turn")
+	return nil
+}
+
+// Guessers share the setter's word and take strict turns. Solving the word
+// makes you the next setter, otherwise the setter role moves round the table.
+func handleTurnGuess(lobby *session.Lobby, playerID string, letter rune) error {
+	guesser := lobby.CurrentGuesser()
+	if guesser == nil || guesser.ID != playerID {
+		return commandError(CodeNotYourTurn, "it isn't your turn to guess")
+	}
+
+	if err := playGuess(&lobby.Game1, letter); err != nil {
+		return err
+	}
+	lobby.AdvanceTurn()
+	BroadcastToLobby(lobby.ID, "update")
+
+	if lobby.Game1.WinOrLost() {
+		if lobby.Game1.Status == game.Won {
+			guesser.WordsSolved++
+			lobby.LastSolver = guesser.ID
+			BroadcastToLobby(lobby.ID, "turnsWin")
+			lobby.SetterID = guesser.ID
+		} else {
+			lobby.LastSolver = ""
+			BroadcastToLobby(lobby.ID, "turnsLose")
+			lobby.RotateSetter()
+		}
+		endTurnRound(lobby)
+		return nil
+	}
+
+	BroadcastToLobby(lobby.ID, "turn")
+	return nil
+}
+
+// Ends the word and goes straight back to waiting for the next setter's word
+func endTurnRound(lobby *session.Lobby) {
+	lobby.State = session.StateEnded
+	BroadcastToLobby(lobby.ID, "report")
+	BroadcastToLobby(lobby.ID, "end")
+	lobby.Round++
+	lobby.State = session.StateWaiting
+	BroadcastToLobby(lobby.ID, "setter")
+}
+
+// Keeps a turn based lobby going when players leave: a missing setter is
+// replaced, and a round with nobody left to guess is abandoned
+func checkTurnGame(lobby *session.Lobby) {
+	setter := lobby.Member(lobby.SetterID)
+	setterLeft := setter == nil || setter.Eliminated
+	if setterLeft {
+		lobby.RotateSetter()
+	}
+
+	if lobby.State != session.StatePlaying {
+		BroadcastToLobby(lobby.ID, "setter")
+		return
+	}
+
+	if setterLeft || lobby.CurrentGuesser() == nil {
+		lobby.LastSolver = ""
+		BroadcastToLobby(lobby.ID, "turnsLose")
+		endTurnRound(lobby)
+		return
+	}
+	BroadcastToLobby(lobby.ID, "turn")
+}
+
+// Practice lobbies can ask the solver for the best letters to guess next.
+// The hint only goes back to the player who asked.
+func handleHint(client *Client, lobbyID string, playerID string) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	if !lobby.Practice {
+		return commandError(CodeWrongMode, "hints are only available in practice lobbies")
+	}
+
+	g := lobby.GameFor(playerID)
+	if g == nil || lobby.State != session.StatePlaying || g.Status != game.InProgress {
+		return commandError(CodeLobbyNotReady, "there's no game to give a hint for")
+	}
+
+	suggestions := solver.Suggest(string(g.Revealed), g.GuessedLetters, lobby.SolverWords())
+	if len(suggestions) > maxHints {
+		suggestions = suggestions[:maxHints]
+	}
+
+	send(client, HintEvent{Type: "hint", Suggestions: suggestions})
+	return nil
+}
+
+// Chat messages in team mode only reach the sender's teammates
+func handleChat(client *Client, lobbyID string, playerID string, req ChatRequest) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	text := req.Text
+	if text == "" {
+		return commandError(CodeInvalidPayload, "chat message is empty")
+	}
+
+	if lobby.Mode != session.ModeTeams {
+		return commandError(CodeWrongMode, "chat is only available to teams")
+	}
+
+	member := lobby.Member(playerID)
+	if member == nil {
+		return commandError(CodeNotInGame, "you aren't on a team")
+	}
+
+	if len(text) > maxChatLength {
+		text = text[:maxChatLength]
+	}
+
+	if err := filter.CheckMessage(text); err != nil {
+		return commandError(CodeFiltered, err.Error())
+	}
+
+	chat_message := ChatEvent{Type: "chat", Team: member.Team, Player: member.ID, Name: member.Name, Message: text}
+	broadcastToTeam(lobby, member.Team, chat_message)
+	return nil
+}
+
+// Starts a race round: both players get their own copy of the same word, so
+// the regular guess path and win/loss messages work unchanged
+func startRaceGame(lobby *session.Lobby) error {
+	entry, err := lobby.PickEntry("")
+	if err != nil {
+		return noWordsToStart(lobby, err)
+	}
+	lobby.Game1 = entry.NewGame()
+	lobby.Game2 = entry.NewGame()
+	lobby.State = session.StatePlaying
+	lobby.Player1Restarted = false
+	lobby.Player2Restarted = false
+	BroadcastToLobby(lobby.ID, "update")
+	BroadcastToLobby(lobby.ID, "start_game")
+	log.Println("New race game created")
+	return nil
+}
+
+// Leaves a round that has no word to start with waiting, with nobody ready,
+// so the players can try again once the word list is sorted out
+func noWordsToStart(lobby *session.Lobby, err error) error {
+	lobby.Game1Ready = false
+	lobby.Game2Ready = false
+	BroadcastToLobby(lobby.ID, "instruction")
+	return commandError(CodeNoWords, err.Error())
+}
+
+// Starts a co-op round on the shared Game1, using the third party's word if one was set
+func startCoopGame(lobby *session.Lobby) error {
+	entry := wordbank.Entry{Word: lobby.CoopWord}
+	if entry.Word == "" {
+		var err error
+		if entry, err = lobby.PickEntry(""); err != nil {
+			return noWordsToStart(lobby, err)
 		}
+	}
+	lobby.CoopWord = ""
+
+	lobby.Game1 = entry.NewGame()
+	lobby.Player1CoopGuesses = nil
+	lobby.Player2CoopGuesses = nil
+	lobby.LastGuessBy = ""
+	lobby.State = session.StatePlaying
+	lobby.Player1Restarted = false
+	lobby.Player2Restarted = false
+	BroadcastToLobby(lobby.ID, "update")
+	BroadcastToLobby(lobby.ID, "start_game")
+	log.Println("New co-op game created")
+	return nil
+}
+
+// Both players guess on the shared Game1, so they share one pool of attempts
+func handleCoopGuess(lobby *session.Lobby, playerID string, letter rune) error {
+	var player string
+	if playerID == lobby.Player1ID {
+		player = "1"
 	} else if playerID == lobby.Player2ID {
-		log.Println("checking player2ID")
-		if game.ValidateWord(word) {
-			log.Println("validating word for player2")
-			lobby.Game2 = game.NewGame(word)
-			lobby.Game2Ready = true
-			BroadcastToLobby(lobbyID, "p2Submit")
-			log.Println("New Game2 created")
+		player = "2"
+	} else {
+		return commandError(CodeNotInGame, "you aren't seated in this lobby")
+	}
+
+	if err := playGuess(&lobby.Game1, letter); err != nil {
+		return err
+	}
+
+	letter = unicode.ToLower(letter)
+	if player == "1" {
+		lobby.Player1CoopGuesses = append(lobby.Player1CoopGuesses, letter)
+	} else {
+		lobby.Player2CoopGuesses = append(lobby.Player2CoopGuesses, letter)
+	}
+	lobby.LastGuessBy = player
+
+	BroadcastToLobby(lobby.ID, "update")
+
+	if lobby.Game1.WinOrLost() {
+		if lobby.Game1.Status == game.Won {
+			BroadcastToLobby(lobby.ID, "coopWin")
+		} else {
+			BroadcastToLobby(lobby.ID, "coopLose")
 		}
+		lobby.State = session.StateEnded
+		BroadcastToLobby(lobby.ID, "report")
+		BroadcastToLobby(lobby.ID, "end")
+	}
+	return nil
+}
+
+// A spectator can set the word for the next co-op round while the lobby is waiting
+func handleCoopWord(client *Client, lobbyID string, spectatorID string, req SubmitRequest) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	word := req.Word
+	if word == "" {
+		return commandError(CodeInvalidPayload, "co-op word is missing")
+	}
+
+	if lobby.State != session.StateWaiting {
+		return commandError(CodeAlreadyStarted, "the co-op round already started")
 	}
 
+	if err := lobby.CheckWord(word); err != nil {
+		return rejectWord(client, spectatorID, err)
+	}
+
+	lobby.CoopWord = strings.ToLower(word)
+	BroadcastToLobby(lobbyID, "coopSubmit")
+	log.Printf("Spectator %s set the co-op word", spectatorID)
+	return nil
+}
+
+func handleSubmit(client *Client, lobbyID string, playerID string, req SubmitRequest) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	if lobby.Mode != session.ModeClassic && lobby.Mode != session.ModeTeams && lobby.Mode != session.ModeTurns {
+		return commandError(CodeWrongMode, "the server picks the word in this mode")
+	}
+
+	var entry wordbank.Entry
+	if req.Random {
+		var err error
+		entry, err = lobby.PickEntry(req.Difficulty)
+		if err != nil {
+			return rejectWord(client, playerID, err)
+		}
+		send(client, RandomWordEvent{Type: "random_word", Word: entry.Word, Category: entry.Category, Clue: entry.Clue, Difficulty: entry.Difficulty})
+	} else {
+		if err := lobby.CheckWord(req.Word); err != nil {
+			return rejectWord(client, playerID, err)
+		}
+		entry.Word = req.Word
+	}
+
+	if lobby.Mode == session.ModeTeams {
+		return handleTeamSubmit(lobby, playerID, entry)
+	}
+
+	if lobby.Mode == session.ModeTurns {
+		return handleTurnSubmit(lobby, playerID, entry)
+	}
+
+	if lobby.State == session.StatePlaying {
+		return commandError(CodeAlreadyStarted, "the words are already set")
+	}
+
+	if playerID == lobby.Player1ID {
+		lobby.Game1 = entry.NewGame()
+		lobby.Game1Ready = true
+		BroadcastToLobby(lobbyID, "p1Submit")
+		log.Println("New Game1 Created")
+	} else if playerID == lobby.Player2ID {
+		lobby.Game2 = entry.NewGame()
+		lobby.Game2Ready = true
+		BroadcastToLobby(lobbyID, "p2Submit")
+		log.Println("New Game2 created")
+	} else {
+		return commandError(CodeNotInGame, "you aren't seated in this lobby")
+	}
+
+	checkClassicStart(lobby)
+	return nil
+}
+
+// Rates a word the setter is considering before they submit it, and says
+// whether the lobby's difficulty rule would let it through
+func handleEstimate(client *Client, lobbyID string, playerID string, req EstimateRequest) error {
+	lobby := wsHub.Lobbies[lobbyID]
+	if req.Word == "" {
+		return commandError(CodeInvalidPayload, "estimate is missing a word")
+	}
+
+	estimate_message := EstimateEvent{Type: "estimate", Word: req.Word, Estimate: lobby.EstimateWord(req.Word), Allowed: true}
+	if err := lobby.CheckWord(req.Word); err != nil {
+		estimate_message.Allowed = false
+		estimate_message.Reason = err.Error()
+	}
+	send(client, estimate_message)
+	return nil
+}
+
+// Turns down a setter's word. Version 1 clients are told in a submit_error,
+// later ones get the invalid_word error reply.
+func rejectWord(client *Client, playerID string, err error) error {
+	log.Printf("Rejected word from %s: %v", playerID, err)
+	if client != nil && client.protocol == ProtocolV1 {
+		send(client, SubmitErrorEvent{Type: "submit_error", Reason: err.Error()})
+	}
+	return commandError(CodeInvalidWord, err.Error())
+}
+
+// Starts a classic game once both words are set
+func checkClassicStart(lobby *session.Lobby) {
 	if lobby.Game1Ready && lobby.Game2Ready {
 		lobby.State = session.StatePlaying
 		lobby.Player1Restarted = false
 		lobby.Player2Restarted = false
-		BroadcastToLobby(lobbyID, "update")
-		BroadcastToLobby(lobbyID, "start_game")
+		BroadcastToLobby(lobby.ID, "update")
+		BroadcastToLobby(lobby.ID, "start_game")
+		if lobby.HasBot() {
+			lobby.BotGame++
+			go runBot(lobby.ID, lobby.BotGame)
+		}
 	}
 }
 
@@ -266,6 +1021,13 @@ func cleanupConnection(lobbyID string, conn *websocket.Conn) {
 	wsHub.Lock.Lock()
 	defer wsHub.Lock.Unlock()
 
+	wsHub.clientsMu.Lock()
+	if client, ok := wsHub.clients[conn]; ok {
+		client.close()
+		delete(wsHub.clients, conn)
+	}
+	wsHub.clientsMu.Unlock()
+
 	lobby, ok := wsHub.Lobbies[lobbyID]
 	if !ok {
 		return
@@ -273,20 +1035,63 @@ func cleanupConnection(lobbyID string, conn *websocket.Conn) {
 
 	// Remove the WebSocket connection
 	lobby.ConnLock.Lock()
+	id := lobby.Clients[conn]
 	delete(lobby.Clients, conn)
 	lobby.ConnLock.Unlock()
 	conn.Close()
-	log.Printf("Len of Clients: %d", len(lobby.Clients))
+
+	leave(lobby, id)
+}
+
+// Tidies up after a viewer's connection or stream has gone, deleting the
+// lobby once nobody is following it. The caller holds wsHub.Lock.
+func leave(lobby *session.Lobby, id string) {
+	lobby.ConnLock.Lock()
+	spectator := lobby.IsSpectator(id)
+	remaining := len(subscribers(lobby))
+	lobby.ConnLock.Unlock()
+
+	if spectator {
+		session.RemoveSpectator(lobby.ID, id)
+	}
+	log.Printf("Len of Clients: %d", remaining)
 
 	// If no more clients are connected, delete the lobby
-	if len(lobby.Clients) == 0 {
-		log.Printf("Lobby %s is empty. Deleting it.", lobbyID)
-		session.DeleteLobby(lobbyID)
-		delete(wsHub.Lobbies, lobbyID)
+	if remaining == 0 {
+		log.Printf("Lobby %s is empty. Deleting it.", lobby.ID)
+		session.DeleteLobby(lobby.ID)
+		delete(wsHub.Lobbies, lobby.ID)
+		delete(wsHub.logs, lobby.ID)
 	}
 }
 
+// Everyone following a lobby, over websockets or event streams. The caller
+// holds the lobby's ConnLock.
+func subscribers(lobby *session.Lobby) []*Client {
+	wsHub.clientsMu.Lock()
+	defer wsHub.clientsMu.Unlock()
+	var clients []*Client
+	for conn := range lobby.Clients {
+		if client, ok := wsHub.clients[conn]; ok {
+			clients = append(clients, client)
+		}
+	}
+	for client := range wsHub.streams[lobby.ID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
 func BroadcastToLobby(lobbyID string, t string) {
+	// reports take a while to analyze, so they are made before the hub is
+	// locked. The caller holds the lobby's StateLock, which keeps the games still.
+	var reports map[*game.Game]any
+	if t == "report" {
+		if lobby, err := session.GetLobby(lobbyID); err == nil {
+			reports = analyzeGames(lobby)
+		}
+	}
+
 	wsHub.Lock.Lock()
 	defer wsHub.Lock.Unlock()
 
@@ -297,78 +1102,457 @@ func BroadcastToLobby(lobbyID string, t string) {
 	}
 	lobby.ConnLock.Lock()
 	defer lobby.ConnLock.Unlock()
-	for conn, id := range lobby.Clients {
-		switch t {
-		case "update":
-			data := map[string]string{"type": "update", "revealed": "", "attempts": "6", "opponent_revealed": "", "opponent_attempts": "6", "guessed_letters": "", "opponent_guessed_letters": ""}
-			if id == lobby.Player1ID {
-				data["revealed"] = string(lobby.Game2.Revealed)
-				data["attempts"] = strconv.Itoa(lobby.Game2.AttemptsLeft)
-				data["opponent_revealed"] = string(lobby.Game1.Revealed)
-				data["opponent_attempts"] = strconv.Itoa(lobby.Game1.AttemptsLeft)
-				data["guessed_letters"] = string(lobby.Game2.GuessedLetters)
-				data["opponent_guessed_letters"] = string(lobby.Game1.GuessedLetters)
-				conn.WriteJSON(data)
-			} else if id == lobby.Player2ID {
-				data["revealed"] = string(lobby.Game1.Revealed)
-				data["attempts"] = strconv.Itoa(lobby.Game1.AttemptsLeft)
-				data["opponent_revealed"] = string(lobby.Game2.Revealed)
-				data["opponent_attempts"] = strconv.Itoa(lobby.Game2.AttemptsLeft)
-				data["guessed_letters"] = string(lobby.Game1.GuessedLetters)
-				data["opponent_guessed_letters"] = string(lobby.Game2.GuessedLetters)
-				conn.WriteJSON(data)
+
+	// every seated player's copy is logged, connected or not, so one who
+	// reconnects can be sent what they missed
+	recipients := make(map[string]bool)
+	for _, id := range []string{lobby.Player1ID, lobby.Player2ID} {
+		if id != "" {
+			recipients[id] = true
+		}
+	}
+	for _, m := range lobby.Members {
+		recipients[m.ID] = true
+	}
+	clients := subscribers(lobby)
+	for _, client := range clients {
+		recipients[client.playerID] = true
+	}
+	// viewers who see the same thing share one event, built and logged once
+	views := make(map[any]any)
+	events := make(map[string]any)
+	for id := range recipients {
+		key := viewKey(t, lobby, id)
+		event, ok := views[key]
+		if !ok {
+			if t == "report" {
+				event = reports[lobby.GameFor(id)]
+			} else {
+				event = lobbyEvent(t, lobby, id)
 			}
-		case "start_game":
-			start_message := map[string]string{"type": "start_game", "revealed": "", "opponent_revealed": ""}
-			if id == lobby.Player1ID {
-				start_message["revealed"] = string(lobby.Game2.Revealed)
-				start_message["opponent_revealed"] = string(lobby.Game1.Revealed)
-				conn.WriteJSON(start_message)
-			} else if id == lobby.Player2ID {
-				start_message["revealed"] = string(lobby.Game1.Revealed)
-				start_message["opponent_revealed"] = string(lobby.Game2.Revealed)
-				conn.WriteJSON(start_message)
+			views[key] = event
+		}
+		if event != nil {
+			events[id] = event
+		}
+	}
+
+	history := logFor(lobbyID)
+	if len(events) > 0 {
+		seq := history.add(events)
+		shared := make(map[any]*sharedEvent)
+		for _, client := range clients {
+			event, ok := events[client.playerID]
+			if !ok {
+				continue
+			}
+			if !client.spectator {
+				client.deliver(seq, event)
+				continue
+			}
+			key := viewKey(t, lobby, client.playerID)
+			if shared[key] == nil {
+				shared[key] = newSharedEvent(seq, event)
 			}
-		case "join":
-			join_message := map[string]string{"type": "join", "message": lobby.Player2}
-			conn.WriteJSON(join_message)
-		case "p1Submit":
-			submit_message := map[string]string{"type": "submit", "player": "1"}
-			conn.WriteJSON(submit_message)
-		case "p2Submit":
-			submit_message := map[string]string{"type": "submit", "player": "2"}
-			conn.WriteJSON(submit_message)
-		case "p1Win":
-			win_message := map[string]string{"type": "win", "player": "1", "word": lobby.Game2.Word}
-			conn.WriteJSON(win_message)
-		case "p2Win":
-			win_message := map[string]string{"type": "win", "player": "2", "word": lobby.Game1.Word}
-			conn.WriteJSON(win_message)
-		case "p1Lose":
-			lose_message := map[string]string{"type": "lost", "player": "1", "word": lobby.Game2.Word}
-			conn.WriteJSON(lose_message)
-		case "p2Lose":
-			lose_message := map[string]string{"type": "lost", "player": "2", "word": lobby.Game1.Word}
-			conn.WriteJSON(lose_message)
-		case "p1Restart":
-			restart_message := map[string]string{"type": "restart", "player": "1"}
-			conn.WriteJSON(restart_message)
-		case "p2Restart":
-			restart_message := map[string]string{"type": "restart", "player": "2"}
-			conn.WriteJSON(restart_message)
-		case "closeAll":
-			data := map[string]string{"type": "close", "message": "close", "player": "1"}
-			conn.WriteJSON(data)
-		case "closeOne":
-			data := map[string]string{"type": "close", "message": "close", "player": "2"}
-			conn.WriteJSON(data)
-		case "end":
-			data := map[string]string{"type": "end", "message": "end"}
-			conn.WriteJSON(data)
-			resetLobby(lobbyID)
+			f, err := shared[key].frameFor(client)
+			if err != nil {
+				log.Println("Failed to encode event:", err)
+				continue
+			}
+			client.queueSpectator(t, f)
 		}
 		log.Printf("Broadcast msg: %s to lobby: %s", t, lobbyID)
 	}
+	if t == "end" {
+		resetLobby(lobbyID)
+	}
+	instructions := make(map[string]InstructionEvent)
+	for id := range recipients {
+		instructions[id] = instructionView(lobby, id)
+	}
+	history.noteInstructions(instructions, len(events) > 0)
+	sendInstructions(lobby)
+}
+
+// Analyzes each game a player finished, once however many players share it
+func analyzeGames(lobby *session.Lobby) map[*game.Game]any {
+	ids := []string{lobby.Player1ID, lobby.Player2ID}
+	for _, m := range lobby.Members {
+		ids = append(ids, m.ID)
+	}
+	reports := make(map[*game.Game]any)
+	for _, id := range ids {
+		g := lobby.GameFor(id)
+		if _, done := reports[g]; done || g == nil || len(g.History) == 0 {
+			continue
+		}
+		reports[g] = ReportEvent{Type: "report", Report: solver.Analyze(g.Word, g.History, lobby.SolverWords())}
+	}
+	return reports
+}
+
+// The view key of spectators, eliminated players and anyone else only watching
+type watcher struct{}
+
+// Which view of a broadcast of type t a viewer gets. Viewers with equal keys
+// are sent the same event.
+func viewKey(t string, lobby *session.Lobby, id string) any {
+	switch t {
+	case "update", "start_game":
+	case "report":
+		return lobby.GameFor(id)
+	default:
+		return nil
+	}
+
+	switch lobby.Mode {
+	case session.ModeCoop:
+		return nil
+	case session.ModeRoyale:
+		if m := lobby.Member(id); m != nil && !m.Eliminated {
+			return id
+		}
+	case session.ModeTurns:
+		if m := lobby.CurrentGuesser(); id == lobby.SetterID || m != nil && m.ID == id {
+			return id
+		}
+	case session.ModeTeams:
+		if m := lobby.Member(id); m != nil {
+			return [2]any{m.Team, lobby.TeamTurn(m.Team) == m}
+		}
+	default:
+		if !lobby.IsSpectator(id) {
+			return id
+		}
+	}
+	return watcher{}
+}
+
+// Builds the event a broadcast of type t sends one viewer, or nil if it sends them nothing
+func lobbyEvent(t string, lobby *session.Lobby, id string) any {
+	switch t {
+	case "update", "start_game":
+		return viewFor(t, lobby, id)
+	case "join":
+		joined := lobby.Player2
+		if lobby.IsMultiplayer() && len(lobby.Members) > 0 {
+			joined = lobby.Members[len(lobby.Members)-1].Name
+		}
+		return JoinEvent{Type: "join", Message: joined, PlayerCount: lobby.PlayerCount}
+	case "spectate":
+		return SpectatorsEvent{Type: "spectators", Count: len(lobby.Spectators)}
+	case "p1Ready":
+		return PlayerEvent{Type: "ready", Player: "1"}
+	case "p2Ready":
+		return PlayerEvent{Type: "ready", Player: "2"}
+	case "coopSubmit":
+		return PlayerEvent{Type: "submit", Player: "spectator"}
+	case "coopWin":
+		return ResultEvent{Type: "win", Player: "team", Word: lobby.Game1.Word}
+	case "coopLose":
+		return ResultEvent{Type: "lost", Player: "team", Word: lobby.Game1.Word}
+	case "race_over":
+		winner, _ := lobby.RaceWinner()
+		race_message := RaceOverEvent{Type: "race_over", Winner: winner, Word: lobby.Game1.Word,
+			Player1RevealedCount: lobby.Game2.RevealedCount(),
+			Player2RevealedCount: lobby.Game1.RevealedCount()}
+		if winner == 1 {
+			race_message.Name = lobby.Player1
+		} else if winner == 2 {
+			race_message.Name = lobby.Player2
+		}
+		return race_message
+	case "eliminated":
+		eliminated_message := EliminatedEvent{Type: "eliminated", Player: lobby.LastEliminated, Round: lobby.Round, Remaining: len(lobby.Alive())}
+		if m := lobby.Member(lobby.LastEliminated); m != nil {
+			eliminated_message.Name = m.Name
+		}
+		return eliminated_message
+	case "next_word":
+		return NextWordEvent{Type: "next_word", Round: lobby.Round, Remaining: len(lobby.Alive())}
+	case "standings":
+		return StandingsEvent{Type: "standings", Round: lobby.Round, Standings: lobby.Standings()}
+	case "royale_winner":
+		winner_message := RoyaleWinnerEvent{Type: "royale_winner"}
+		if alive := lobby.Alive(); len(alive) == 1 {
+			winner_message.Player = alive[0].ID
+			winner_message.Name = alive[0].Name
+		}
+		return winner_message
+	case "t1Submit":
+		return PlayerEvent{Type: "submit", Team: 1}
+	case "t2Submit":
+		return PlayerEvent{Type: "submit", Team: 2}
+	case "t1Win":
+		return ResultEvent{Type: "win", Team: 1, Word: lobby.Game2.Word}
+	case "t2Win":
+		return ResultEvent{Type: "win", Team: 2, Word: lobby.Game1.Word}
+	case "t1Lose":
+		return ResultEvent{Type: "lost", Team: 1, Word: lobby.Game2.Word}
+	case "t2Lose":
+		return ResultEvent{Type: "lost", Team: 2, Word: lobby.Game1.Word}
+	case "turn":
+		if lobby.Mode == session.ModeTurns {
+			turn_message := TurnEvent{Type: "turn"}
+			if m := lobby.CurrentGuesser(); m != nil {
+				turn_message.Player = m.ID
+				turn_message.Name = m.Name
+			}
+			return turn_message
+		}
+		turn_message := TeamTurnEvent{Type: "turn"}
+		if m := lobby.TeamTurn(1); m != nil {
+			turn_message.Team1Player = m.ID
+			turn_message.Team1Name = m.Name
+		}
+		if m := lobby.TeamTurn(2); m != nil {
+			turn_message.Team2Player = m.ID
+			turn_message.Team2Name = m.Name
+		}
+		return turn_message
+	case "setter":
+		setter_message := SetterEvent{Type: "setter", Player: lobby.SetterID, Round: lobby.Round}
+		if m := lobby.Member(lobby.SetterID); m != nil {
+			setter_message.Name = m.Name
+		}
+		return setter_message
+	case "turnsWin":
+		win_message := ResultEvent{Type: "win", Player: lobby.LastSolver, Word: lobby.Game1.Word}
+		if m := lobby.Member(lobby.LastSolver); m != nil {
+			win_message.Name = m.Name
+		}
+		return win_message
+	case "turnsLose":
+		return ResultEvent{Type: "lost", Word: lobby.Game1.Word}
+	case "p1Submit":
+		return PlayerEvent{Type: "submit", Player: "1"}
+	case "p2Submit":
+		return PlayerEvent{Type: "submit", Player: "2"}
+	case "p1Win":
+		return ResultEvent{Type: "win", Player: "1", Word: lobby.Game2.Word}
+	case "p2Win":
+		return ResultEvent{Type: "win", Player: "2", Word: lobby.Game1.Word}
+	case "p1Lose":
+		return ResultEvent{Type: "lost", Player: "1", Word: lobby.Game2.Word}
+	case "p2Lose":
+		return ResultEvent{Type: "lost", Player: "2", Word: lobby.Game1.Word}
+	case "p1Restart":
+		return PlayerEvent{Type: "restart", Player: "1"}
+	case "p2Restart":
+		return PlayerEvent{Type: "restart", Player: "2"}
+	case "closeAll":
+		return PlayerEvent{Type: "close", Message: "close", Player: "1"}
+	case "closeOne":
+		return PlayerEvent{Type: "close", Message: "close", Player: "2"}
+	case "round":
+		return RoundEvent{Type: "round", Round: lobby.Round, BestOf: lobby.BestOf,
+			Player1Score: lobby.Player1Score, Player2Score: lobby.Player2Score}
+	case "next_round":
+		return NextRoundEvent{Type: "next_round", Round: lobby.Round, BestOf: lobby.BestOf}
+	case "series_end":
+		// a series that ran all its rounds level is a tie, winner 0
+		winner, winnerName := lobby.SeriesWinner(), ""
+		switch winner {
+		case 1:
+			winnerName = lobby.Player1
+		case 2:
+			winnerName = lobby.Player2
+		}
+		return SeriesEndEvent{Type: "series_end", Winner: winner, Name: winnerName,
+			Player1Score: lobby.Player1Score, Player2Score: lobby.Player2Score}
+	case "end":
+		return EndEvent{Type: "end", Message: "end"}
+	}
+	return nil
+}
+
+// Sends each player their instruction if it changed since they last heard.
+// The caller holds the lobby's ConnLock.
+func sendInstructions(lobby *session.Lobby) {
+	for _, client := range subscribers(lobby) {
+		event := instructionView(lobby, client.playerID)
+		if event == client.instruction {
+			continue
+		}
+		client.instruction = event
+		send(client, event)
+	}
+}
+
+// Builds the instruction event for one viewer, spectators have none
+func instructionView(lobby *session.Lobby, id string) InstructionEvent {
+	own := lobby.InstructionFor(id)
+	opp := lobby.OpponentInstructionFor(id)
+	return InstructionEvent{Type: "instruction", Phase: own.Phase, Instruction: own.Prompt,
+		OpponentPhase: opp.Phase, OpponentInstruction: opp.Prompt}
+}
+
+// Builds the board a viewer sees in the lobby's mode, or nil if they have none
+func viewFor(t string, lobby *session.Lobby, id string) any {
+	switch lobby.Mode {
+	case session.ModeRoyale:
+		return royaleView(t, lobby, id)
+	case session.ModeTurns:
+		return turnView(t, lobby, id)
+	case session.ModeTeams:
+		return teamView(t, lobby, id)
+	case session.ModeCoop:
+		// co-op players and spectators all look at the same shared board
+		return coopView(t, lobby)
+	}
+
+	if id == lobby.Player1ID {
+		return boardView(t, lobby.Game2, lobby.Game1)
+	} else if id == lobby.Player2ID {
+		return boardView(t, lobby.Game1, lobby.Game2)
+	} else if lobby.IsSpectator(id) {
+		return spectatorView(t, lobby)
+	}
+	return nil
+}
+
+// Builds a classic or race player's view: the board they're guessing and their opponent's
+func boardView(t string, board game.Game, opponent game.Game) BoardEvent {
+	return BoardEvent{
+		Type:                   t,
+		Revealed:               string(board.Revealed),
+		Attempts:               board.AttemptsLeft,
+		OpponentRevealed:       string(opponent.Revealed),
+		OpponentAttempts:       opponent.AttemptsLeft,
+		GuessedLetters:         string(board.GuessedLetters),
+		OpponentGuessedLetters: string(opponent.GuessedLetters),
+		Category:               board.Category,
+		Clue:                   board.Clue,
+	}
+}
+
+// Builds the read-only view sent to spectators. Player boards are named after the
+// player doing the guessing, so player1's board is Game2. Hidden words are never included.
+func spectatorView(t string, lobby *session.Lobby) SpectatorBoardEvent {
+	return SpectatorBoardEvent{
+		Type:                  t,
+		Spectator:             true,
+		Player1:               lobby.Player1,
+		Player2:               lobby.Player2,
+		Player1Revealed:       string(lobby.Game2.Revealed),
+		Player1Attempts:       lobby.Game2.AttemptsLeft,
+		Player1GuessedLetters: string(lobby.Game2.GuessedLetters),
+		Player1Category:       lobby.Game2.Category,
+		Player2Revealed:       string(lobby.Game1.Revealed),
+		Player2Attempts:       lobby.Game1.AttemptsLeft,
+		Player2GuessedLetters: string(lobby.Game1.GuessedLetters),
+		Player2Category:       lobby.Game1.Category,
+	}
+}
+
+// Builds a battle royale board. Surviving players see their own game, eliminated
+// players and spectators watch the standings instead.
+func royaleView(t string, lobby *session.Lobby, id string) RoyaleBoardEvent {
+	view := RoyaleBoardEvent{
+		Type:      t,
+		Mode:      string(session.ModeRoyale),
+		Round:     lobby.Round,
+		Remaining: len(lobby.Alive()),
+	}
+
+	member := lobby.Member(id)
+	if member == nil || member.Eliminated {
+		view.Spectator = true
+		view.Standings = lobby.Standings()
+		return view
+	}
+
+	view.Revealed = string(member.Game.Revealed)
+	view.Attempts = member.Game.AttemptsLeft
+	view.GuessedLetters = string(member.Game.GuessedLetters)
+	view.Category = member.Game.Category
+	view.Clue = member.Game.Clue
+	return view
+}
+
+// Builds the shared co-op board, including which player guessed which letters
+func coopView(t string, lobby *session.Lobby) CoopBoardEvent {
+	lastName := ""
+	if lobby.LastGuessBy == "1" {
+		lastName = lobby.Player1
+	} else if lobby.LastGuessBy == "2" {
+		lastName = lobby.Player2
+	}
+	return CoopBoardEvent{
+		Type:                  t,
+		Mode:                  string(session.ModeCoop),
+		Revealed:              string(lobby.Game1.Revealed),
+		Attempts:              lobby.Game1.AttemptsLeft,
+		GuessedLetters:        string(lobby.Game1.GuessedLetters),
+		Player1GuessedLetters: string(lobby.Player1CoopGuesses),
+		Player2GuessedLetters: string(lobby.Player2CoopGuesses),
+		LastGuessPlayer:       lobby.LastGuessBy,
+		LastGuessName:         lastName,
+		Category:              lobby.Game1.Category,
+		Clue:                  lobby.Game1.Clue,
+	}
+}
+
+// Sends a message only to the connected members of one team
+func broadcastToTeam(lobby *session.Lobby, team int, message any) {
+	lobby.ConnLock.Lock()
+	defer lobby.ConnLock.Unlock()
+	for _, client := range subscribers(lobby) {
+		if m := lobby.Member(client.playerID); m != nil && m.Team == team {
+			send(client, message)
+		}
+	}
+}
+
+// Builds the turn based board everyone shares. The setter already knows the
+// word, everyone else only sees what has been revealed.
+func turnView(t string, lobby *session.Lobby, id string) TurnBoardEvent {
+	view := TurnBoardEvent{
+		Type:           t,
+		Mode:           string(session.ModeTurns),
+		Revealed:       string(lobby.Game1.Revealed),
+		Attempts:       lobby.Game1.AttemptsLeft,
+		GuessedLetters: string(lobby.Game1.GuessedLetters),
+		Category:       lobby.Game1.Category,
+		Clue:           lobby.Game1.Clue,
+		Setter:         lobby.SetterID,
+	}
+	if id == lobby.SetterID {
+		view.Word = lobby.Game1.Word
+	}
+	if m := lobby.CurrentGuesser(); m != nil {
+		view.Turn = m.ID
+		view.YourTurn = m.ID == id
+	}
+	return view
+}
+
+// Builds a team board: each member sees the word their team is guessing,
+// the other team's progress and whether it's their turn
+func teamView(t string, lobby *session.Lobby, id string) any {
+	member := lobby.Member(id)
+	if member == nil {
+		return spectatorView(t, lobby)
+	}
+
+	board, opponent := lobby.Game2, lobby.Game1
+	if member.Team == 2 {
+		board, opponent = lobby.Game1, lobby.Game2
+	}
+	return TeamBoardEvent{
+		Type:                   t,
+		Mode:                   string(session.ModeTeams),
+		Team:                   member.Team,
+		YourTurn:               lobby.TeamTurn(member.Team) == member,
+		Revealed:               string(board.Revealed),
+		Attempts:               board.AttemptsLeft,
+		GuessedLetters:         string(board.GuessedLetters),
+		Category:               board.Category,
+		Clue:                   board.Clue,
+		OpponentRevealed:       string(opponent.Revealed),
+		OpponentAttempts:       opponent.AttemptsLeft,
+		OpponentGuessedLetters: string(opponent.GuessedLetters),
+	}
 }
 
 func resetLobby(lobbyID string) {
@@ -377,8 +1561,16 @@ func resetLobby(lobbyID string) {
 		log.Fatalln("Lobby not found while resetting")
 	}
 
+	// instructions tell who won from the last result once the boards are gone
+	result := &session.Result{Game1: lobby.Game1, Game2: lobby.Game2}
+	if lobby.Mode == session.ModeRace {
+		result.RaceWinner, _ = lobby.RaceWinner()
+	}
+	lobby.LastResult = result
 	lobby.Game1Ready = false
 	lobby.Game2Ready = false
 	lobby.Game1 = game.Game{}
 	lobby.Game2 = game.Game{}
+	lobby.Player1CoopGuesses = nil
+	lobby.Player2CoopGuesses = nil
 }